@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/jaeles-project/jaeles/libs"
+	"github.com/jaeles-project/jaeles/oob"
+	"github.com/jaeles-project/jaeles/utils"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	var oobCmd = &cobra.Command{
+		Use:   "oob",
+		Short: "Start out of band interaction server (HTTP + DNS)",
+		Long:  libs.Banner(),
+		RunE:  runOOB,
+	}
+	oobCmd.Flags().String("http", "0.0.0.0:80", "Address of HTTP listener")
+	oobCmd.Flags().String("dns", "0.0.0.0:53", "Address of DNS listener (blank to disable)")
+	oobCmd.Flags().String("ip", "", "Public IP to answer DNS A query with")
+	oobCmd.Flags().String("secret", "", "Secret to get interactions, required unless listening on loopback (use the same value in --oob-secret when scanning)")
+	oobCmd.Flags().Int("max-interactions", oob.DefaultMaxInteractions, "Max interactions kept in memory, oldest ones are dropped first")
+	oobCmd.Flags().Int("ttl", int(oob.DefaultInteractionTTL.Hours()), "Hours to keep interactions in memory")
+	oobCmd.SetHelpFunc(OOBHelp)
+	RootCmd.AddCommand(oobCmd)
+}
+
+func runOOB(cmd *cobra.Command, _ []string) error {
+	httpAddr, _ := cmd.Flags().GetString("http")
+	dnsAddr, _ := cmd.Flags().GetString("dns")
+	ip, _ := cmd.Flags().GetString("ip")
+	secret, _ := cmd.Flags().GetString("secret")
+	maxInteractions, _ := cmd.Flags().GetInt("max-interactions")
+	ttl, _ := cmd.Flags().GetInt("ttl")
+	// anyone could read interactions from the API without secret
+	if secret == "" && !oob.IsLoopback(httpAddr) {
+		utils.ErrorF("--secret is required when HTTP listener is not on loopback: %v", httpAddr)
+		os.Exit(1)
+	}
+
+	server := oob.NewServer(ip, secret)
+	server.MaxInteractions = maxInteractions
+	server.TTL = time.Duration(ttl) * time.Hour
+	errs := make(chan error, 2)
+	if dnsAddr != "" {
+		go func() {
+			errs <- server.ListenDNS(dnsAddr)
+		}()
+	}
+	go func() {
+		errs <- server.ListenHTTP(httpAddr)
+	}()
+	err := <-errs
+	utils.ErrorF("Error running OOB server: %v", err)
+	os.Exit(1)
+	return nil
+}
+
+// OOBHelp oob help message
+func OOBHelp(cmd *cobra.Command, _ []string) {
+	fmt.Println(libs.Banner())
+	fmt.Println(cmd.UsageString())
+	h := "\nExamples Commands:\n"
+	h += "  jaeles oob --ip 1.2.3.4 --secret s3cr3t\n"
+	h += "  jaeles scan -s <signature> -u <url> --oob-host oob.example.com --oob-secret s3cr3t\n"
+	h += "  jaeles oob --http 127.0.0.1:8088 --dns ''\n"
+	h += "  jaeles scan -s <signature> -u <url> --oob-host 127.0.0.1:8088\n"
+	fmt.Println(h)
+	fmt.Printf("Official Documentation can be found here: %s\n", color.GreenString(libs.DOCS))
+}
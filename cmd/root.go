@@ -44,16 +44,42 @@ func init() {
 	RootCmd.PersistentFlags().StringVarP(&options.SignFolder, "signDir", "B", "~/.jaeles/base-signatures/", "Folder contain default signatures")
 	RootCmd.PersistentFlags().StringVar(&options.ScanID, "scanID", "", "Scan ID")
 	// http options
-	RootCmd.PersistentFlags().StringVar(&options.Proxy, "proxy", "", "proxy")
+	RootCmd.PersistentFlags().StringVar(&options.Proxy, "proxy", "", "Proxy (http, https, socks5 and socks5h are supported)")
+	RootCmd.PersistentFlags().StringVar(&options.ProxyFile, "proxy-file", "", "File contain list of proxies to rotate")
+	RootCmd.PersistentFlags().StringVar(&options.ProxyRotate, "proxy-rotate", "round-robin", "Proxy rotation: round-robin or random")
 	RootCmd.PersistentFlags().IntVar(&options.Timeout, "timeout", 20, "HTTP timeout")
 	RootCmd.PersistentFlags().IntVar(&options.Retry, "retry", 0, "HTTP Retry")
-	RootCmd.PersistentFlags().IntVar(&options.Delay, "delay", 0, "Delay time between requests")
+	RootCmd.PersistentFlags().IntVar(&options.ChromeTabs, "chrome-tabs", 5, "Max tabs of the pooled browser for chrome engine")
+	RootCmd.PersistentFlags().IntVar(&options.Delay, "delay", 0, "Delay time in seconds between requests to the same host")
+	RootCmd.PersistentFlags().IntVar(&options.RateLimit, "rate-limit", 0, "Max requests per second to the same host (0 is unlimited)")
+	RootCmd.PersistentFlags().IntVar(&options.MaxPerHost, "max-per-host", 0, "Max in-flight requests to the same host (0 is unlimited)")
+	RootCmd.PersistentFlags().IntVar(&options.MaxPerIP, "max-per-ip", 0, "Max in-flight requests to the same IP (0 is unlimited)")
+	RootCmd.PersistentFlags().StringVar(&options.AuthFile, "auth", "", "Authentication profile file (login and re-login automatically)")
+	// out of band options
+	RootCmd.PersistentFlags().StringVar(&options.OOBHost, "oob-host", "", "Host of OOB server used in {{.oob}} payload (e.g: oob.example.com or 1.2.3.4:8088)")
+	RootCmd.PersistentFlags().StringVar(&options.OOBServer, "oob-server", "", "URL of OOB server to get interactions (default is http://<oob-host>)")
+	RootCmd.PersistentFlags().StringVar(&options.OOBSecret, "oob-secret", "", "Secret of OOB server")
+	RootCmd.PersistentFlags().IntVar(&options.OOBWait, "oob-wait", 5, "Seconds OOBHit() wait for interactions")
+	RootCmd.PersistentFlags().IntVar(&options.OOBGrace, "oob-grace", 30, "Seconds to wait for late OOB interactions before exiting")
+	RootCmd.PersistentFlags().IntVar(&options.ScriptTimeout, "script-timeout", 30, "Seconds a detection script can run before being stopped")
+	// TLS options
+	RootCmd.PersistentFlags().StringVar(&options.TLS.Cert, "tls-cert", "", "Client certificate file for mTLS")
+	RootCmd.PersistentFlags().StringVar(&options.TLS.Key, "tls-key", "", "Client private key file for mTLS")
+	RootCmd.PersistentFlags().StringVar(&options.TLS.CA, "tls-ca", "", "Custom CA bundle to verify server certificate")
+	RootCmd.PersistentFlags().StringVar(&options.TLS.MinVersion, "tls-min", "", "Minimum TLS version (e.g: 1.2)")
+	RootCmd.PersistentFlags().StringVar(&options.TLS.MaxVersion, "tls-max", "", "Maximum TLS version (e.g: 1.3)")
+	RootCmd.PersistentFlags().StringSliceVar(&options.TLS.Ciphers, "tls-ciphers", []string{}, "TLS cipher suites (e.g: TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256)")
+	RootCmd.PersistentFlags().StringVar(&options.TLS.SNI, "sni", "", "Override TLS server name")
 	// output options
 	RootCmd.PersistentFlags().StringVarP(&options.Output, "output", "o", "out", "Output folder name")
 	RootCmd.PersistentFlags().BoolVar(&options.JsonOutput, "json", false, "Store output as JSON")
 	RootCmd.PersistentFlags().StringVar(&options.PassiveOutput, "passiveOutput", "", "Passive output folder (default is passive-out)")
 	RootCmd.PersistentFlags().StringVar(&options.PassiveSummary, "passiveSummary", "", "Passive Summary file")
 	RootCmd.PersistentFlags().StringVarP(&options.SummaryOutput, "summaryOutput", "O", "", "Summary output file")
+	RootCmd.PersistentFlags().StringVar(&options.HarFile, "har", "", "Record every request and response to HAR file")
+	RootCmd.PersistentFlags().StringVar(&options.RecordDir, "record", "", "Record responses to folder to replay them later")
+	RootCmd.PersistentFlags().StringVar(&options.ReplayDir, "replay", "", "Replay recorded responses from folder instead of sending requests")
+	RootCmd.PersistentFlags().BoolVar(&options.Screenshot, "screenshot", false, "Take screenshot of every HTML finding (always on for chrome engine)")
 	RootCmd.PersistentFlags().StringVar(&options.SummaryVuln, "summaryVuln", "", "Summary output file")
 	RootCmd.PersistentFlags().BoolVar(&options.VerboseSummary, "sverbose", false, "Store verbose info in summary file")
 	// report options
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/jaeles-project/jaeles/core"
 	"github.com/jaeles-project/jaeles/libs"
+	"github.com/jaeles-project/jaeles/sender"
 	"github.com/jaeles-project/jaeles/utils"
 	"github.com/panjf2000/ants"
 	"github.com/spf13/cobra"
@@ -100,6 +101,34 @@ func runScan(cmd *cobra.Command, _ []string) error {
 
 	/* ---- Really start do something ---- */
 
+	// record all traffic from here
+	if options.HarFile != "" {
+		if err := sender.InitHAR(options.HarFile); err != nil {
+			utils.ErrorF("Error creating HAR file: %v", err)
+			os.Exit(1)
+		}
+	}
+
+	// load proxy pool before sending anything
+	if options.ProxyFile != "" {
+		if err := sender.InitProxyPool(options); err != nil {
+			utils.ErrorF("Error loading proxies: %v", err)
+			os.Exit(1)
+		}
+	}
+
+	// login with authentication profile
+	if options.AuthFile != "" {
+		profile, err := core.ParseAuth(options.AuthFile)
+		if err == nil {
+			err = sender.InitAuth(options, profile, core.ExtractResponse)
+		}
+		if err != nil {
+			utils.ErrorF("Error loading auth profile: %v", err)
+			os.Exit(1)
+		}
+	}
+
 	// run background detector
 	if !options.NoBackGround {
 		go func() {
@@ -111,10 +140,21 @@ func runScan(cmd *cobra.Command, _ []string) error {
 
 	var wg sync.WaitGroup
 	p, _ := ants.NewPoolWithFunc(options.Concurrency, func(i interface{}) {
-		CreateRunner(i)
+		scheduled := i.(sender.Scheduled)
+		job := scheduled.Job.(libs.Job)
+		if scheduled.Slot != nil {
+			job.Slot = scheduled.Slot
+		}
+		CreateRunner(job)
+		scheduled.Slot.Release()
 		wg.Done()
 	}, ants.WithPreAlloc(true))
 	defer p.Release()
+	// jobs of a host that hit the limit wait in queue so other hosts keep the pool busy
+	scheduler := sender.NewHostScheduler(options, p.Invoke, func(i interface{}, err error) {
+		utils.ErrorF("Error running job of %v: %v", i.(libs.Job).URL, err)
+		wg.Done()
+	})
 
 	for _, url := range urls {
 		// calculate filtering result first if enabled from cli
@@ -129,15 +169,22 @@ func runScan(cmd *cobra.Command, _ []string) error {
 				continue
 			}
 			sign.Checksums = baseJob.Checksums
+			sign.Baselines = baseJob.Baselines
 
 			wg.Add(1)
 			// Submit tasks one by one.
 			job := libs.Job{URL: url, Sign: sign}
-			_ = p.Invoke(job)
+			scheduler.Submit(url, job)
 		}
 	}
 
 	wg.Wait()
+	scheduler.Close()
+	core.WaitOOB(options)
+	sender.CloseTransports()
+	sender.CloseChrome()
+	sender.CloseHAR()
+	sender.ReplayReport(options)
 	CleanOutput()
 
 	if options.Scan.EnableGenReport && utils.FolderExists(options.Output) {
@@ -149,11 +196,18 @@ func runScan(cmd *cobra.Command, _ []string) error {
 func CreateRunner(j interface{}) {
 	var jobs []libs.Job
 	rawJob := j.(libs.Job)
+	// requests of the job go through the slot the scheduler reserved
+	options := options
+	options.Slot = rawJob.Slot
 
 	if rawJob.Sign.Type == "dns" {
 		CreateDnsRunner(rawJob)
 		return
 	}
+	if rawJob.Sign.Type == "network" {
+		CreateNetworkRunner(rawJob)
+		return
+	}
 
 	// enable local analyze
 	if options.LocalAnalyze {
@@ -215,6 +269,24 @@ func CreateDnsRunner(job libs.Job) {
 	runner.Resolving()
 }
 
+// CreateNetworkRunner create runner for raw TCP/TLS signature, replicate ports if needed
+func CreateNetworkRunner(job libs.Job) {
+	jobs := []libs.Job{job}
+	if job.Sign.Replicate.Ports != "" && !options.Mics.DisableReplicate {
+		moreJobs, err := core.ReplicationJob(job.URL, job.Sign)
+		if err == nil && len(moreJobs) > 0 {
+			jobs = moreJobs
+		}
+	}
+	for _, job := range jobs {
+		runner, err := core.InitNetworkRunner(job.URL, job.Sign, options)
+		if err != nil {
+			utils.ErrorF("Error create new network runner: %v", err)
+		}
+		runner.Connecting()
+	}
+}
+
 /////////////////////// Chunk options (very experimental)
 
 func genChunkFiles(urlFile string, options libs.Options) []string {
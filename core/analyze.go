@@ -21,7 +21,11 @@ func (r *Record) Analyze() {
 		r.Origins = r.Sign.Origins
 	}
 
+	// extract before detection so findings carry the values
+	r.Extract()
 	r.Detector()
+	// interaction found by detections is already reported, only track the request after that
+	TrackOOB(*r)
 	if r.Opt.Mics.AlwaysTrue {
 		r.IsVulnerable = true
 		r.Output()
@@ -0,0 +1,40 @@
+package core
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/jaeles-project/jaeles/libs"
+	"github.com/jaeles-project/jaeles/utils"
+	"gopkg.in/yaml.v2"
+)
+
+// ExtractResponse get value of the extractor from a response without a record, used for the login response
+func ExtractResponse(res libs.Response, extractor libs.Extractor) (string, bool) {
+	return Extract(Record{Response: res}, extractor)
+}
+
+// ParseAuth parse authentication profile from YAML file
+func ParseAuth(authFile string) (auth libs.AuthProfile, err error) {
+	yamlFile, err := ioutil.ReadFile(utils.NormalizePath(authFile))
+	if err != nil {
+		return auth, err
+	}
+	err = yaml.Unmarshal(yamlFile, &auth)
+	if err != nil {
+		return auth, err
+	}
+	if auth.Login.URL == "" {
+		return auth, fmt.Errorf("no login request in %v", authFile)
+	}
+	if auth.Login.Method == "" {
+		auth.Login.Method = "GET"
+	}
+
+	auth.InjectHeaders = make(map[string]string)
+	for k, v := range ParseRawHeaders(auth.Headers) {
+		auth.InjectHeaders[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return auth, nil
+}
@@ -12,9 +12,8 @@ func Background(options libs.Options) {
 	utils.DebugF("Checking backround task")
 	time.Sleep(time.Duration(options.Refresh) * time.Second)
 
-	// @NOTE: disable for now
-	//PollingLog()
-	//PickupLog(options)
+	// report late interactions of OOB requests
+	PollOOB(options)
 	// @TODO: Add passive signature for analyzer each request
 }
 
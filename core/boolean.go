@@ -0,0 +1,79 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/jaeles-project/jaeles/libs"
+	"github.com/jaeles-project/jaeles/utils"
+)
+
+// similarity of body to count two responses as the same
+const defaultPairThreshold = 0.95
+
+// PairResponse responses of true and false payload at the same insertion point
+type PairResponse struct {
+	True  libs.Response
+	False libs.Response
+}
+
+// SendPairs send true and false request of every pair,
+// request and response of the first true payload are kept for the record
+func (r *Record) SendPairs(req *libs.Request) libs.Response {
+	var res libs.Response
+	r.PairResponses = nil
+	for index, pair := range req.Pairs {
+		trueReq, falseReq := pair.True, pair.False
+		AltResolveRequest(&trueReq)
+		AltResolveRequest(&falseReq)
+		trueRes := SendRequest(r.Opt, &trueReq)
+		falseRes := SendRequest(r.Opt, &falseReq)
+		utils.DebugF("[Pair] %v %v -- %v %v", trueReq.URL, trueRes.StatusCode, falseReq.URL, falseRes.StatusCode)
+		r.PairResponses = append(r.PairResponses, PairResponse{True: trueRes, False: falseRes})
+		if index == 0 {
+			pairs := req.Pairs
+			*req = trueReq
+			req.Pairs = pairs
+			res = trueRes
+		}
+	}
+	return res
+}
+
+// BooleanDiff check if true response match the origin while false one differ in at least confirmations pairs,
+// true and false response just need to differ when there is no origin. Pair with a failed request never confirm
+func BooleanDiff(origin libs.Response, pairs []PairResponse, confirmations int, threshold float64) (string, bool) {
+	if len(pairs) == 0 {
+		return "", false
+	}
+	if confirmations <= 0 || confirmations > len(pairs) {
+		confirmations = len(pairs)
+	}
+	hasOrigin := origin.StatusCode != 0
+	var confirmed, failed int
+	for _, pair := range pairs {
+		// failed request is inconclusive, dropped connection of a flaky host or WAF isn't a difference
+		if pair.True.StatusCode == 0 || pair.False.StatusCode == 0 {
+			failed++
+			continue
+		}
+		if hasOrigin {
+			if sameResponse(pair.True, origin, threshold) && !sameResponse(pair.False, origin, threshold) {
+				confirmed++
+			}
+			continue
+		}
+		if !sameResponse(pair.True, pair.False, threshold) {
+			confirmed++
+		}
+	}
+	first := pairs[0]
+	summary := fmt.Sprintf("%v/%v pairs confirmed, %v failed, true %v similarity %.2f, false %v similarity %.2f",
+		confirmed, len(pairs), failed, first.True.StatusCode, Similarity(first.True.Body, origin.Body),
+		first.False.StatusCode, Similarity(first.False.Body, origin.Body))
+	return summary, confirmed >= confirmations
+}
+
+// responses have the same status and similar body
+func sameResponse(a libs.Response, b libs.Response, threshold float64) bool {
+	return a.StatusCode == b.StatusCode && Similarity(a.Body, b.Body) >= threshold
+}
@@ -0,0 +1,99 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jaeles-project/jaeles/libs"
+)
+
+func TestBooleanDiff(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// id is injectable, false condition return nothing
+		id := r.URL.Query().Get("id")
+		if strings.Contains(id, "'1'='2") || strings.Contains(id, "'x'='y") {
+			fmt.Fprint(w, "No item found")
+			return
+		}
+		fmt.Fprintf(w, "Item 1: a long description of the first item, name %v", r.URL.Query().Get("name"))
+	}))
+	defer ts.Close()
+
+	opt := libs.Options{Concurrency: 1, Threads: 1, NoDB: true, NoOutput: true}
+	signContent := `
+id: boolean-01
+type: fuzz
+info:
+  name: Boolean test
+
+origin:
+  method: GET
+  url: >-
+    {{.URL}}
+
+pairs:
+  - true: "' AND '1'='1"
+    false: "' AND '1'='2"
+  - true: "' AND 'x'='x"
+    false: "' AND 'x'='y"
+
+requests:
+  - generators:
+      - Query("[[.original]]{{.payload}}")
+    detections:
+      - >-
+        BooleanDiff()
+`
+	sign, err := ParseSignFromContent(signContent)
+	if err != nil {
+		t.Fatalf("Error parsing signature")
+	}
+	runner, err := InitRunner(ts.URL+"/?id=1&name=a", sign, opt)
+	if err != nil {
+		t.Fatalf("Error init runner")
+	}
+	runner.InRoutine = true
+	runner.Sending()
+	if len(runner.Records) != 2 {
+		t.Fatalf("Expect one record for each insertion point, got %v", len(runner.Records))
+	}
+	for _, rec := range runner.Records {
+		if len(rec.PairResponses) != 2 {
+			t.Errorf("Expect 2 pairs sent, got %v", len(rec.PairResponses))
+		}
+		injectable := strings.Contains(rec.Request.URL, "id=1%27")
+		if rec.IsVulnerable != injectable {
+			t.Errorf("Wrong detection of %v: %v", rec.Request.URL, rec.ExtraOutput)
+		}
+	}
+
+	pairs := []PairResponse{
+		{True: libs.Response{StatusCode: 200, Body: "a b c"}, False: libs.Response{StatusCode: 500, Body: "a b c"}},
+		{True: libs.Response{StatusCode: 200, Body: "a b c"}, False: libs.Response{StatusCode: 200, Body: "a b c"}},
+	}
+	origin := libs.Response{StatusCode: 200, Body: "a b c"}
+	if _, ok := BooleanDiff(origin, pairs, 0, defaultPairThreshold); ok {
+		t.Errorf("Expect all pairs to be confirmed by default")
+	}
+	if _, ok := BooleanDiff(origin, pairs, 1, defaultPairThreshold); !ok {
+		t.Errorf("Expect one confirmation is enough")
+	}
+
+	// failed request doesn't count as a difference
+	failed := []PairResponse{
+		{True: libs.Response{StatusCode: 200, Body: "a b c"}, False: libs.Response{}},
+		{True: libs.Response{}, False: libs.Response{StatusCode: 200, Body: "a b c"}},
+	}
+	if _, ok := BooleanDiff(origin, failed, 1, defaultPairThreshold); ok {
+		t.Errorf("Expect pairs with failed request to be inconclusive")
+	}
+	if _, ok := BooleanDiff(libs.Response{}, failed, 1, defaultPairThreshold); ok {
+		t.Errorf("Expect pairs with failed request to be inconclusive without origin")
+	}
+	if _, ok := BooleanDiff(origin, append(failed, pairs[0]), 0, defaultPairThreshold); ok {
+		t.Errorf("Expect failed pairs not to be confirmed")
+	}
+}
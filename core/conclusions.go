@@ -16,7 +16,20 @@ func (r *Record) Condition() bool {
 
 // Conclude is main function for detections
 func (r *Record) Conclude() {
-	record := *r
+	conclude := concludeVMs.Get(r)
+	defer concludeVMs.Put(conclude)
+	timeout := scriptTimeout(r)
+
+	for _, concludeScript := range r.Request.Conclusions {
+		utils.DebugF("[Conclude]: %v", concludeScript)
+		conclude.Run(concludeScript, timeout)
+	}
+}
+
+// newConcludeVM register conclusion functions, they work on the record bound to the VM
+func newConcludeVM() *scriptVM {
+	var r *Record
+	var record Record
 	vm := otto.New()
 
 	// ExecCmd execute command command
@@ -112,6 +125,53 @@ func (r *Record) Conclude() {
 		return otto.Value{}
 	})
 
+	// query structured response, use with SetValue to keep the value
+	//  - SetValue("role", JsonPath("body", "$.data.user.role"))
+	vm.Set("JsonPath", func(call otto.FunctionCall) otto.Value {
+		component := GetComponent(record, call.Argument(0).String())
+		value, _ := JsonPath(component, call.Argument(1).String())
+		result, _ := vm.ToValue(value)
+		return result
+	})
+
+	vm.Set("JsonExist", func(call otto.FunctionCall) otto.Value {
+		component := GetComponent(record, call.Argument(0).String())
+		result, _ := vm.ToValue(JsonExist(component, call.Argument(1).String()))
+		return result
+	})
+
+	vm.Set("JsonType", func(call otto.FunctionCall) otto.Value {
+		component := GetComponent(record, call.Argument(0).String())
+		result, _ := vm.ToValue(JsonType(component, call.Argument(1).String()))
+		return result
+	})
+
+	vm.Set("XPath", func(call otto.FunctionCall) otto.Value {
+		component := GetComponent(record, call.Argument(0).String())
+		value, _ := XPath(component, call.Argument(1).String())
+		result, _ := vm.ToValue(value)
+		return result
+	})
+
+	vm.Set("HtmlSelect", func(call otto.FunctionCall) otto.Value {
+		component := GetComponent(record, call.Argument(0).String())
+		var attr string
+		if len(call.ArgumentList) > 2 {
+			attr = call.Argument(2).String()
+		}
+		value, _ := HtmlSelect(component, call.Argument(1).String(), attr)
+		result, _ := vm.ToValue(value)
+		return result
+	})
+
+	// contexts of payload reflections, to pick payloads of the next request
+	//  - SetValue("context", ReflectionContext())
+	vm.Set("ReflectionContext", func(call otto.FunctionCall) otto.Value {
+		contexts := ReflectionContexts(RecordReflections(record))
+		result, _ := vm.ToValue(strings.Join(contexts, ","))
+		return result
+	})
+
 	// SetValue("var_name", StatusCode())
 	// SetValue("status", StringCount('middleware', '11'))
 	vm.Set("SetValue", func(call otto.FunctionCall) otto.Value {
@@ -122,9 +182,18 @@ func (r *Record) Conclude() {
 		return otto.Value{}
 	})
 
-	for _, concludeScript := range record.Request.Conclusions {
-		utils.DebugF("[Conclude]: %v", concludeScript)
-		vm.Run(concludeScript)
+	return &scriptVM{
+		vm: vm,
+		bind: func(rec *Record) {
+			r = rec
+			record = Record{}
+			if rec != nil {
+				record = *rec
+			}
+		},
+		output: func() string {
+			return ""
+		},
 	}
 }
 
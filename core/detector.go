@@ -30,7 +30,87 @@ func (r *Record) RequestScripts(scriptType string, scripts []string) bool {
 		return false
 	}
 
-	record := *r
+	detector := detectorVMs.Get(r)
+	defer detectorVMs.Put(detector)
+	timeout := scriptTimeout(r)
+
+	/* Really start do detection here */
+	switch scriptType {
+	case "detect", "detections":
+		for _, analyze := range scripts {
+			// pass detection here
+			result, _ := detector.Run(analyze, timeout)
+			analyzeResult, err := result.Export()
+			// in case vm panic
+			if err != nil || analyzeResult == nil {
+				r.DetectString = analyze
+				r.IsVulnerable = false
+				r.DetectResult = ""
+				r.ExtraOutput = ""
+				continue
+			}
+			r.DetectString = analyze
+			// only true boolean count, compare values of query functions explicitly
+			r.IsVulnerable = analyzeResult == true
+			r.DetectResult = detector.output()
+			r.ExtraOutput = detector.output()
+
+			utils.DebugF("[Detection] %v -- %v", analyze, r.IsVulnerable)
+			// deal with vulnerable one here
+			next := r.Output()
+			if next == "stop" {
+				return true
+			}
+		}
+		return r.IsVulnerable
+	case "condition", "conditions":
+		var valid bool
+		for _, analyze := range scripts {
+			result, _ := detector.Run(analyze, timeout)
+			analyzeResult, err := result.Export()
+			// in case vm panic
+			if err != nil || analyzeResult == nil {
+				r.PassCondition = false
+				continue
+			}
+			r.PassCondition = analyzeResult == true
+			utils.DebugF("[Condition] %v -- %v", analyze, r.PassCondition)
+			valid = r.PassCondition
+		}
+		return valid
+	case "pass", "passive", "passives":
+		for _, analyze := range scripts {
+			// pass detection here
+			result, _ := detector.Run(analyze, timeout)
+			analyzeResult, err := result.Export()
+			// in case vm panic
+			if err != nil || analyzeResult == nil {
+				r.PassiveString = analyze
+				r.IsVulnerablePassive = false
+				r.PassiveMatch = ""
+				continue
+			}
+			r.PassiveString = analyze
+			r.IsVulnerablePassive = analyzeResult == true
+			r.PassiveMatch = detector.output()
+
+			utils.DebugF("[PassiveDetect] %v -- %v", analyze, r.IsVulnerablePassive)
+			// deal with vulnerable one here
+			next := r.PassiveOutput()
+			if next == "stop" {
+				return true
+			}
+		}
+		return r.IsVulnerablePassive
+	}
+
+	return false
+}
+
+// newDetectorVM register detection functions, they work on the record bound to the VM
+func newDetectorVM() *scriptVM {
+	var r *Record
+	var record Record
 	var extra string
 	vm := otto.New()
 
@@ -244,6 +324,40 @@ func (r *Record) RequestScripts(scriptType string, scripts []string) bool {
 		return result
 	})
 
+	// number of responses read on the connection, pipelined ones of raw engine included
+	vm.Set("ResponseCount", func(call otto.FunctionCall) otto.Value {
+		count := len(record.Response.Responses)
+		if count == 0 && record.Response.StatusCode != 0 {
+			count = 1
+		}
+		result, _ := vm.ToValue(count)
+		return result
+	})
+
+	// status code of the nth pipelined response, the first one is 0
+	vm.Set("PipelineStatus", func(call otto.FunctionCall) otto.Value {
+		index, _ := call.Argument(0).ToInteger()
+		pipelined, ok := PipelineRecord(record, int(index))
+		if !ok {
+			return otto.Value{}
+		}
+		result, _ := vm.ToValue(pipelined.Response.StatusCode)
+		return result
+	})
+
+	// search in component of the nth pipelined response like PipelineSearch(1, "body", "admin")
+	vm.Set("PipelineSearch", func(call otto.FunctionCall) otto.Value {
+		index, _ := call.Argument(0).ToInteger()
+		pipelined, ok := PipelineRecord(record, int(index))
+		if !ok {
+			result, _ := vm.ToValue(false)
+			return result
+		}
+		component := GetComponent(pipelined, call.Argument(1).String())
+		result, _ := vm.ToValue(StringSearch(component, call.Argument(2).String()))
+		return result
+	})
+
 	// if checksum is different with all previous checksum
 	vm.Set("Diff", func(call otto.FunctionCall) otto.Value {
 		rchecksum := record.Response.Checksum
@@ -260,6 +374,150 @@ func (r *Record) RequestScripts(scriptType string, scripts []string) bool {
 		return result
 	})
 
+	// fuzzy comparison with the origin, origins by label or index, or "baseline" of filtering,
+	// the closest one is used when there are many, undefined when there is nothing to compare with
+	//  - Similarity() > 0.9
+	//  - Similarity("baseline") < 0.8
+	//  - LengthDelta("false-case", "body") < 50
+	vm.Set("Similarity", func(call otto.FunctionCall) otto.Value {
+		current, components := compareArguments(record, call.ArgumentList)
+		if len(components) == 0 {
+			utils.DebugF("Similarity() -- nothing to compare with")
+			return otto.Value{}
+		}
+		var similarity float64
+		for _, component := range components {
+			if value := Similarity(current, component); value > similarity {
+				similarity = value
+			}
+		}
+		utils.DebugF("Similarity() -- %v", similarity)
+		result, _ := vm.ToValue(similarity)
+		return result
+	})
+
+	vm.Set("LengthDelta", func(call otto.FunctionCall) otto.Value {
+		current, components := compareArguments(record, call.ArgumentList)
+		if len(components) == 0 {
+			utils.DebugF("LengthDelta() -- nothing to compare with")
+			return otto.Value{}
+		}
+		delta := -1
+		for _, component := range components {
+			if value := LengthDelta(current, component); delta < 0 || value < delta {
+				delta = value
+			}
+		}
+		utils.DebugF("LengthDelta() -- %v", delta)
+		result, _ := vm.ToValue(delta)
+		return result
+	})
+
+	vm.Set("LineDiff", func(call otto.FunctionCall) otto.Value {
+		current, components := compareArguments(record, call.ArgumentList)
+		if len(components) == 0 {
+			utils.DebugF("LineDiff() -- nothing to compare with")
+			return otto.Value{}
+		}
+		diff := -1
+		for _, component := range components {
+			if value := LineDiff(current, component); diff < 0 || value < diff {
+				diff = value
+			}
+		}
+		utils.DebugF("LineDiff() -- %v", diff)
+		result, _ := vm.ToValue(diff)
+		return result
+	})
+
+	// time-based detection with samples of timing mode, delay is the timing of request by default
+	//  - TimingSignificant(5)
+	vm.Set("TimingSignificant", func(call otto.FunctionCall) otto.Value {
+		delay := float64(record.Request.Timing)
+		if len(call.ArgumentList) > 0 {
+			delay, _ = call.Argument(0).ToFloat()
+		}
+		summary, validate := TimingSignificant(record.TimingSamples, delay)
+		utils.DebugF("TimingSignificant() -- %v -- %v", summary, validate)
+		if validate {
+			extra = summary
+		}
+		result, _ := vm.ToValue(validate)
+		return result
+	})
+
+	// boolean differential detection with responses of payload pairs,
+	// true one match the origin while false one differ in at least confirmations pairs
+	//  - BooleanDiff()
+	//  - BooleanDiff(2, 0.9)
+	vm.Set("BooleanDiff", func(call otto.FunctionCall) otto.Value {
+		confirmations := len(record.PairResponses)
+		threshold := defaultPairThreshold
+		if len(call.ArgumentList) > 0 {
+			value, _ := call.Argument(0).ToInteger()
+			confirmations = int(value)
+		}
+		if len(call.ArgumentList) > 1 {
+			threshold, _ = call.Argument(1).ToFloat()
+		}
+		summary, validate := BooleanDiff(record.OriginRes, record.PairResponses, confirmations, threshold)
+		utils.DebugF("BooleanDiff() -- %v -- %v", summary, validate)
+		if validate {
+			extra = summary
+		}
+		result, _ := vm.ToValue(validate)
+		return result
+	})
+
+	// contexts of payload reflections in the body: html, attribute-quoted, attribute-unquoted,
+	// script-string, script-code, comment, url or json, empty when payload is not reflected
+	//  - ReflectionContext().indexOf("attribute-quoted") != -1
+	vm.Set("ReflectionContext", func(call otto.FunctionCall) otto.Value {
+		reflections := RecordReflections(record)
+		var summary []string
+		for _, reflection := range reflections {
+			summary = append(summary, reflection.String())
+		}
+		utils.DebugF("ReflectionContext() -- %v", summary)
+		if len(summary) > 0 {
+			extra = strings.Join(summary, ", ")
+		}
+		result, _ := vm.ToValue(strings.Join(ReflectionContexts(reflections), ","))
+		return result
+	})
+
+	// payload reflected with all chars unencoded, in the context if it's given
+	//  - Reflected("<>")
+	//  - Reflected("\"", "attribute-quoted")
+	vm.Set("Reflected", func(call otto.FunctionCall) otto.Value {
+		chars := call.Argument(0).String()
+		var context string
+		if len(call.ArgumentList) > 1 {
+			context = call.Argument(1).String()
+		}
+		var validate bool
+		for _, reflection := range RecordReflections(record) {
+			if context != "" && reflection.Context != context {
+				continue
+			}
+			survived := true
+			for _, c := range chars {
+				if !strings.ContainsRune(reflection.Chars, c) {
+					survived = false
+					break
+				}
+			}
+			if survived {
+				validate = true
+				extra = reflection.String()
+				break
+			}
+		}
+		utils.DebugF("Reflected(%v) -- %v", chars, validate)
+		result, _ := vm.ToValue(validate)
+		return result
+	})
+
 	// Origin field
 	vm.Set("OriginStatusCode", func(call otto.FunctionCall) otto.Value {
 		statusCode := record.OriginRes.StatusCode
@@ -418,6 +676,117 @@ func (r *Record) RequestScripts(scriptType string, scripts []string) bool {
 		return result
 	})
 
+	// Cookie get cookie value of the session
+	//  - Cookie("PHPSESSID")
+	vm.Set("Cookie", func(call otto.FunctionCall) otto.Value {
+		cookieName := call.Argument(0).String()
+		if value, ok := record.Request.Target["cookie."+cookieName]; ok {
+			result, _ := vm.ToValue(value)
+			return result
+		}
+		result, _ := vm.ToValue(false)
+		return result
+	})
+
+	// query structured response, found value is used as extra output
+	//  - JsonPath("body", "$.data.user.role") == "admin"
+	vm.Set("JsonPath", func(call otto.FunctionCall) otto.Value {
+		component := GetComponent(record, call.Argument(0).String())
+		value, found := JsonPath(component, call.Argument(1).String())
+		if found {
+			extra = value
+		}
+		result, _ := vm.ToValue(value)
+		return result
+	})
+
+	//  - JsonExist("body", "$.data.token")
+	vm.Set("JsonExist", func(call otto.FunctionCall) otto.Value {
+		component := GetComponent(record, call.Argument(0).String())
+		result, _ := vm.ToValue(JsonExist(component, call.Argument(1).String()))
+		return result
+	})
+
+	//  - JsonType("body", "$.data.id") == "number"
+	vm.Set("JsonType", func(call otto.FunctionCall) otto.Value {
+		component := GetComponent(record, call.Argument(0).String())
+		result, _ := vm.ToValue(JsonType(component, call.Argument(1).String()))
+		return result
+	})
+
+	//  - XPath("body", "//user[@id='1']/role") == "admin"
+	vm.Set("XPath", func(call otto.FunctionCall) otto.Value {
+		component := GetComponent(record, call.Argument(0).String())
+		value, found := XPath(component, call.Argument(1).String())
+		if found {
+			extra = value
+		}
+		result, _ := vm.ToValue(value)
+		return result
+	})
+
+	//  - HtmlSelect("body", "form#login input[name=csrf]", "value")
+	vm.Set("HtmlSelect", func(call otto.FunctionCall) otto.Value {
+		component := GetComponent(record, call.Argument(0).String())
+		var attr string
+		if len(call.ArgumentList) > 2 {
+			attr = call.Argument(2).String()
+		}
+		value, found := HtmlSelect(component, call.Argument(1).String(), attr)
+		if found {
+			extra = value
+		}
+		result, _ := vm.ToValue(value)
+		return result
+	})
+
+	// search in console messages and uncaught errors of chrome engine
+	vm.Set("ConsoleSearch", func(call otto.FunctionCall) otto.Value {
+		browser := record.Response.Browser
+		component := strings.Join(append(browser.Console, browser.Errors...), "\n")
+		result, _ := vm.ToValue(StringSearch(component, call.Argument(0).String()))
+		return result
+	})
+
+	// check if input reached a DOM sink like SinkHit("jaeles", "innerHTML")
+	vm.Set("SinkHit", func(call otto.FunctionCall) otto.Value {
+		analyzeString := call.Argument(0).String()
+		var sink string
+		if len(call.ArgumentList) > 1 {
+			sink = call.Argument(1).String()
+		}
+		result, _ := vm.ToValue(SinkHit(record.Response.Browser.Sinks, analyzeString, sink))
+		return result
+	})
+
+	// search in every request the page made
+	vm.Set("NetworkSearch", func(call otto.FunctionCall) otto.Value {
+		component := strings.Join(record.Response.Browser.Requests, "\n")
+		result, _ := vm.ToValue(StringSearch(component, call.Argument(0).String()))
+		return result
+	})
+
+	// search in navigations and redirects of the page
+	vm.Set("NavigationSearch", func(call otto.FunctionCall) otto.Value {
+		component := strings.Join(record.Response.Browser.Navigations, "\n")
+		result, _ := vm.ToValue(StringSearch(component, call.Argument(0).String()))
+		return result
+	})
+
+	// wait for interaction of {{.oob}} like OOBHit() or OOBHit("dns")
+	vm.Set("OOBHit", func(call otto.FunctionCall) otto.Value {
+		var protocol string
+		if len(call.ArgumentList) > 0 {
+			protocol = call.Argument(0).String()
+		}
+		interactions := OOBHit(record, protocol)
+		if len(interactions) > 0 {
+			extra = interactions
+		}
+		result, _ := vm.ToValue(interactions != "")
+		return result
+	})
+
 	// check if folder, file exist or not
 	vm.Set("Exist", func(call otto.FunctionCall) otto.Value {
 		input := utils.NormalizePath(call.Argument(0).String())
@@ -444,76 +813,20 @@ func (r *Record) RequestScripts(scriptType string, scripts []string) bool {
 		return result
 	})
 
-	/* Really start do detection here */
-	switch scriptType {
-	case "detect", "detections":
-		for _, analyze := range scripts {
-			// pass detection here
-			result, _ := vm.Run(analyze)
-			analyzeResult, err := result.Export()
-			// in case vm panic
-			if err != nil || analyzeResult == nil {
-				r.DetectString = analyze
-				r.IsVulnerable = false
-				r.DetectResult = ""
-				r.ExtraOutput = ""
-				continue
-			}
-			r.DetectString = analyze
-			r.IsVulnerable = analyzeResult.(bool)
-			r.DetectResult = extra
-			r.ExtraOutput = extra
-
-			utils.DebugF("[Detection] %v -- %v", analyze, r.IsVulnerable)
-			// deal with vulnerable one here
-			next := r.Output()
-			if next == "stop" {
-				return true
-			}
-		}
-		return r.IsVulnerable
-	case "condition", "conditions":
-		var valid bool
-		for _, analyze := range scripts {
-			result, _ := vm.Run(analyze)
-			analyzeResult, err := result.Export()
-			// in case vm panic
-			if err != nil || analyzeResult == nil {
-				r.PassCondition = false
-				continue
-			}
-			r.PassCondition = analyzeResult.(bool)
-			utils.DebugF("[Condition] %v -- %v", analyze, r.PassCondition)
-			valid = r.PassCondition
-		}
-		return valid
-	case "pass", "passive", "passives":
-		for _, analyze := range scripts {
-			// pass detection here
-			result, _ := vm.Run(analyze)
-			analyzeResult, err := result.Export()
-			// in case vm panic
-			if err != nil || analyzeResult == nil {
-				r.PassiveString = analyze
-				r.IsVulnerablePassive = false
-				r.PassiveMatch = ""
-				continue
-			}
-			r.PassiveString = analyze
-			r.IsVulnerablePassive = analyzeResult.(bool)
-			r.PassiveMatch = extra
-
-			utils.DebugF("[PassiveDetect] %v -- %v", analyze, r.IsVulnerablePassive)
-			// deal with vulnerable one here
-			next := r.PassiveOutput()
-			if next == "stop" {
-				return true
+	return &scriptVM{
+		vm: vm,
+		bind: func(rec *Record) {
+			r = rec
+			record = Record{}
+			if rec != nil {
+				record = *rec
 			}
-		}
-		return r.IsVulnerablePassive
+			extra = ""
+		},
+		output: func() string {
+			return extra
+		},
 	}
-
-	return false
 }
 
 //////////////
@@ -581,6 +894,18 @@ func GetComponent(record Record, component string) string {
 	}
 }
 
+// PipelineRecord get record with the nth response read on the connection as its response
+func PipelineRecord(record Record, index int) (Record, bool) {
+	if len(record.Response.Responses) == 0 && index == 0 {
+		return record, true
+	}
+	if index < 0 || index >= len(record.Response.Responses) {
+		return record, false
+	}
+	record.Response = record.Response.Responses[index]
+	return record, true
+}
+
 // StringSearch search string literal in component
 func StringSearch(component string, analyzeString string) bool {
 	var result bool
@@ -591,6 +916,24 @@ func StringSearch(component string, analyzeString string) bool {
 	return result
 }
 
+// SinkHit check if string reached a sink, empty sink mean any of them
+func SinkHit(sinks []string, analyzeString string, sink string) bool {
+	for _, hit := range sinks {
+		parts := strings.SplitN(hit, ": ", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		if sink != "" && !strings.EqualFold(parts[0], sink) {
+			continue
+		}
+		if strings.Contains(parts[1], analyzeString) {
+			utils.DebugF("Sink hit: %v", hit)
+			return true
+		}
+	}
+	return false
+}
+
 // StringCount count string literal in component
 func StringCount(component string, analyzeString string) int {
 	return strings.Count(component, analyzeString)
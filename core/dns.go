@@ -63,7 +63,47 @@ func (r *Runner) DnsDetection() {
 }
 
 func (r *Record) DnsDetector() bool {
-	record := *r
+	detector := dnsVMs.Get(r)
+	defer dnsVMs.Put(detector)
+	timeout := scriptTimeout(r)
+
+	// really run detection here
+	for _, analyze := range r.Dns.Detections {
+		// pass detection here
+		result, _ := detector.Run(analyze, timeout)
+		analyzeResult, err := result.Export()
+		// in case vm panic
+		if err != nil || analyzeResult == nil {
+			r.DetectString = analyze
+			r.IsVulnerable = false
+			r.DetectResult = ""
+			r.ExtraOutput = ""
+			continue
+		}
+		r.DetectString = analyze
+		r.IsVulnerable = analyzeResult.(bool)
+		r.DetectResult = detector.output()
+		r.ExtraOutput = detector.output()
+
+		// add extra things for standard output
+		r.Request.URL = r.Dns.Domain
+		r.Request.Beautify = fmt.Sprintf("dig %s %s @%s", r.Dns.RecordType, r.Dns.Domain, r.Dns.Resolver)
+
+		utils.DebugF("[Detection] %v -- %v", analyze, r.IsVulnerable)
+		// deal with vulnerable one here
+		next := r.Output()
+		if next == "stop" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// newDnsVM register DNS detection functions, they work on the record bound to the VM
+func newDnsVM() *scriptVM {
+	var r *Record
+	var record Record
 	var extra string
 	vm := otto.New()
 
@@ -77,7 +117,7 @@ func (r *Record) DnsDetector() bool {
 			recordName = args[0].String()
 		}
 		content := GetDnsComponent(record, recordName)
-		record.Response.Beautify = content
+		r.Response.Beautify = content
 		result, _ := vm.ToValue(StringSearch(content, searchString))
 		return result
 	})
@@ -91,7 +131,7 @@ func (r *Record) DnsDetector() bool {
 			recordName = args[0].String()
 		}
 		content := GetDnsComponent(record, recordName)
-		record.Response.Beautify = content
+		r.Response.Beautify = content
 
 		matches, validate := RegexSearch(content, searchString)
 		result, err := vm.ToValue(validate)
@@ -105,38 +145,20 @@ func (r *Record) DnsDetector() bool {
 		return result
 	})
 
-	// really run detection here
-	for _, analyze := range record.Dns.Detections {
-		// pass detection here
-		result, _ := vm.Run(analyze)
-		analyzeResult, err := result.Export()
-		// in case vm panic
-		if err != nil || analyzeResult == nil {
-			r.DetectString = analyze
-			r.IsVulnerable = false
-			r.DetectResult = ""
-			r.ExtraOutput = ""
-			continue
-		}
-		r.DetectString = analyze
-		r.IsVulnerable = analyzeResult.(bool)
-		r.DetectResult = extra
-		r.ExtraOutput = extra
-
-		// add extra things for standard output
-		r.Request.URL = r.Dns.Domain
-		r.Request.Beautify = fmt.Sprintf("dig %s %s @%s", r.Dns.RecordType, r.Dns.Domain, r.Dns.Resolver)
-		r.Response.Beautify = record.Response.Beautify
-
-		utils.DebugF("[Detection] %v -- %v", analyze, r.IsVulnerable)
-		// deal with vulnerable one here
-		next := r.Output()
-		if next == "stop" {
-			return true
-		}
+	return &scriptVM{
+		vm: vm,
+		bind: func(rec *Record) {
+			r = rec
+			record = Record{}
+			if rec != nil {
+				record = *rec
+			}
+			extra = ""
+		},
+		output: func() string {
+			return extra
+		},
 	}
-
-	return false
 }
 
 func GetDnsComponent(record Record, componentName string) string {
@@ -0,0 +1,116 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jaeles-project/jaeles/libs"
+	"github.com/jaeles-project/jaeles/utils"
+)
+
+// Extract run extractors of the request, outputs are kept in the record
+// and available as [[.name]] to later requests of serial signature
+func (r *Record) Extract() {
+	if len(r.Request.Extractors) == 0 {
+		return
+	}
+	if r.Extracted == nil {
+		r.Extracted = make(map[string]string)
+	}
+	// requests of parallel signature share the target so only serial one can write to it
+	shared := r.Request.Target != nil && r.Serial
+	for _, extractor := range r.Request.Extractors {
+		if extractor.Name == "" {
+			utils.ErrorF("Extractor without name: %v", extractor.Value)
+			continue
+		}
+		value, ok := Extract(*r, extractor)
+		utils.DebugF("[Extractor] %v -- %v", extractor.Name, value)
+		if !ok {
+			continue
+		}
+		r.Extracted[extractor.Name] = value
+		if shared {
+			r.Request.Target[extractor.Name] = value
+		}
+	}
+}
+
+// Extract get value of the extractor from the record
+func Extract(record Record, extractor libs.Extractor) (string, bool) {
+	switch strings.ToLower(extractor.Type) {
+	case "json", "jsonpath":
+		part := extractor.Part
+		if part == "" {
+			part = "body"
+		}
+		return JsonPath(GetComponent(record, part), extractor.Value)
+	case "header":
+		for _, header := range record.Response.Headers {
+			for k, v := range header {
+				if strings.EqualFold(k, extractor.Value) {
+					return v, true
+				}
+			}
+		}
+	case "cookie":
+		for _, cookie := range ResponseCookies(record.Response) {
+			if cookie.Name == extractor.Value {
+				return cookie.Value, true
+			}
+		}
+	default:
+		part := extractor.Part
+		if part == "" {
+			part = "response"
+		}
+		re, err := regexp.Compile(extractor.Value)
+		if err != nil {
+			utils.ErrorF("Error Regex: %v", extractor.Value)
+			return "", false
+		}
+		matches := re.FindStringSubmatch(GetComponent(record, part))
+		if matches == nil {
+			return "", false
+		}
+		group := extractor.Group
+		if group == 0 && len(matches) > 1 {
+			group = 1
+		}
+		if group >= len(matches) {
+			return "", false
+		}
+		return matches[group], true
+	}
+	return "", false
+}
+
+// ResponseCookies parse cookies from Set-Cookie headers of the response
+func ResponseCookies(res libs.Response) []*http.Cookie {
+	header := http.Header{}
+	for _, h := range res.Headers {
+		for k, v := range h {
+			if strings.EqualFold(k, "Set-Cookie") {
+				header.Add("Set-Cookie", v)
+			}
+		}
+	}
+	return (&http.Response{Header: header}).Cookies()
+}
+
+// FormatExtracted format outputs of extractors as name=value sorted by name
+func FormatExtracted(extracted map[string]string) []string {
+	var names []string
+	for name := range extracted {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var outputs []string
+	for _, name := range names {
+		outputs = append(outputs, fmt.Sprintf("%v=%v", name, extracted[name]))
+	}
+	return outputs
+}
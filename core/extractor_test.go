@@ -0,0 +1,129 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/jaeles-project/jaeles/libs"
+	"github.com/jaeles-project/jaeles/utils"
+	jsoniter "github.com/json-iterator/go"
+)
+
+func TestExtractors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			w.Header().Set("X-Request-Id", "req-1")
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: "s3cr3t"})
+			fmt.Fprint(w, `{"data": {"token": "t0ken"}}`)
+			return
+		}
+		if r.Header.Get("Authorization") == "Bearer t0ken" {
+			fmt.Fprint(w, "version: 1.2.3 welcome admin")
+		}
+	}))
+	defer ts.Close()
+
+	output := t.TempDir()
+	opt := libs.Options{
+		Concurrency:   3,
+		Threads:       5,
+		NoDB:          true,
+		JsonOutput:    true,
+		Output:        output,
+		SummaryOutput: path.Join(output, "jaeles-summary.txt"),
+		SummaryVuln:   path.Join(output, "vuln-summary.txt"),
+	}
+	signContent := `
+id: extractor-01
+serial: true
+info:
+  name: Extractor test
+  risk: High
+
+requests:
+  - method: GET
+    url: >-
+      {{.BaseURL}}/login
+    extractors:
+      - name: token
+        type: json
+        value: $.data.token
+      - name: request_id
+        type: header
+        value: X-Request-Id
+      - name: sid
+        type: cookie
+        value: sid
+  - method: GET
+    url: >-
+      {{.BaseURL}}/admin
+    headers:
+      - Authorization: Bearer [[.token]]
+    extractors:
+      - name: version
+        type: regex
+        value: 'version: ([\d.]+)'
+    detections:
+      - >-
+        StringSearch("body", "welcome admin")
+`
+	sign, err := ParseSignFromContent(signContent)
+	if err != nil {
+		t.Fatalf("Error parsing signature")
+	}
+	runner, err := InitRunner(ts.URL, sign, opt)
+	if err != nil {
+		t.Fatalf("Error init runner")
+	}
+	runner.InRoutine = true
+	runner.Sending()
+	if len(runner.Records) != 2 {
+		t.Fatalf("Expect 2 records, got %v", len(runner.Records))
+	}
+	first := runner.Records[0]
+	if first.Extracted["token"] != "t0ken" || first.Extracted["request_id"] != "req-1" || first.Extracted["sid"] != "s3cr3t" {
+		t.Errorf("Wrong extracted values: %v", first.Extracted)
+	}
+	second := runner.Records[1]
+	if !second.IsVulnerable {
+		t.Fatalf("Expect token carried to the next request")
+	}
+	if second.Extracted["token"] != "t0ken" || second.Extracted["version"] != "1.2.3" {
+		t.Errorf("Expect values of earlier request in the finding, got %v", second.Extracted)
+	}
+
+	summary := utils.ReadingLines(opt.SummaryOutput)
+	if len(summary) != 1 {
+		t.Fatalf("Expect 1 finding in summary, got %v", summary)
+	}
+	var vulnData libs.VulnData
+	if err := jsoniter.UnmarshalFromString(summary[0], &vulnData); err != nil {
+		t.Fatal(err)
+	}
+	if vulnData.Extracted["version"] != "1.2.3" || vulnData.Extracted["token"] != "t0ken" {
+		t.Errorf("Expect extracted value in summary, got %v", summary[0])
+	}
+	if !strings.Contains(utils.GetFileContent(vulnData.OutputFile), `"version":"1.2.3"`) {
+		t.Errorf("Expect extracted value in output file")
+	}
+
+	// session signature is sent one by one too
+	sign, err = ParseSignFromContent(strings.Replace(signContent, "serial: true", "session: true", 1))
+	if err != nil || !sign.Session || sign.Serial {
+		t.Fatalf("Error parsing session signature")
+	}
+	opt.NoOutput = true
+	runner, err = InitRunner(ts.URL, sign, opt)
+	if err != nil {
+		t.Fatalf("Error init runner")
+	}
+	runner.InRoutine = true
+	runner.Sending()
+	if len(runner.Records) != 2 || !runner.Records[1].IsVulnerable {
+		t.Errorf("Expect token carried to the next request of session signature")
+	}
+}
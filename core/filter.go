@@ -32,7 +32,11 @@ func BaseCalculateFiltering(job *libs.Job, options libs.Options) {
 		req.EnableChecksum = true
 		req.URL = filteringURL
 
+		release := sender.AcquireHost(options, req.URL)
+		sender.WaitHost(options, req.URL)
 		res, err := sender.JustSend(options, req)
+		release()
+		sender.ThrottleHost(options, req.URL, res)
 		// in case of timeout or anything, just ignore it
 		if err != nil {
 			return
@@ -47,6 +51,7 @@ func BaseCalculateFiltering(job *libs.Job, options libs.Options) {
 			utils.DebugF("[Checksum] %s - %s", req.URL, res.Checksum)
 			job.Checksums = append(job.Checksums, res.Checksum)
 		}
+		job.Baselines = append(job.Baselines, res)
 	}
 	job.Checksums = funk.UniqString(job.Checksums)
 }
@@ -57,6 +62,7 @@ func CalculateFiltering(job *libs.Job, options libs.Options) {
 	// ignore the base result if enabled from signature
 	if job.Sign.OverrideFilerPaths {
 		job.Sign.Checksums = []string{}
+		job.Sign.Baselines = []libs.Response{}
 	} else {
 		// mean doesn't have --fi in cli
 		if len(job.Sign.Checksums) == 0 {
@@ -82,8 +88,13 @@ func CalculateFiltering(job *libs.Job, options libs.Options) {
 		req.Method = "GET"
 		req.EnableChecksum = true
 		req.URL = filteringURL
+		req.SignID = job.Sign.ID
 
+		release := sender.AcquireHost(options, req.URL)
+		sender.WaitHost(options, req.URL)
 		res, err := sender.JustSend(options, req)
+		release()
+		sender.ThrottleHost(options, req.URL, res)
 		// in case of timeout or anything
 		if err != nil {
 			return
@@ -98,6 +109,7 @@ func CalculateFiltering(job *libs.Job, options libs.Options) {
 			utils.DebugF("[Checksum] %s - %s", req.URL, res.Checksum)
 			job.Sign.Checksums = append(job.Sign.Checksums, res.Checksum)
 		}
+		job.Sign.Baselines = append(job.Sign.Baselines, res)
 	}
 
 	job.Sign.Checksums = funk.UniqString(job.Sign.Checksums)
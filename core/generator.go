@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/url"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -15,61 +16,134 @@ import (
 )
 
 // Generators run multiple generator
-func Generators(req libs.Request, sign libs.Signature) []libs.Request {
+func Generators(req libs.Request, sign libs.Signature, options libs.Options) []libs.Request {
+	if len(sign.Pairs) > 0 {
+		return PairGenerators(req, sign, options)
+	}
 	var reqs []libs.Request
 	realPayloads := funk.UniqString(ParsePayloads(sign))
 	for _, payload := range realPayloads {
-		fuzzReq := req
-		// prepare something so we can access variable in generator string too
-		payload = ResolveVariable(payload, fuzzReq.Target)
-		fuzzReq.Target["payload"] = payload
-		// set original to blank first
-		fuzzReq.Target["original"] = ""
-		fuzzReq.Detections = ResolveDetection(fuzzReq.Detections, fuzzReq.Target)
-		//fuzzReq.Middlewares = ResolveDetection(fuzzReq.Middlewares, fuzzReq.Target)
-		fuzzReq.Generators = funk.UniqString(ResolveDetection(fuzzReq.Generators, fuzzReq.Target))
-
-		// in case we want to send normal request with no generator
-		if len(fuzzReq.Generators) == 0 && fuzzReq.Method != "" {
-			reqs = append(reqs, fuzzReq)
-		}
-
-		// really gen requests
-		for _, genString := range fuzzReq.Generators {
-			// just copy exactly request again
-			if genString == "Null()" {
-				reqs = append(reqs, fuzzReq)
-				continue
-			}
-			if fuzzReq.Method == "" {
-				fuzzReq.Method = "GET"
-			}
+		reqs = append(reqs, PayloadRequests(req, payload, options)...)
+	}
+	return reqs
+}
 
-			utils.DebugF("[Generator] %v", genString)
-			injectedReqs := RunGenerator(fuzzReq, genString)
-			if len(injectedReqs) <= 0 {
-				utils.DebugF("No request generated by: %v", genString)
+// PairGenerators gen requests for true and false payload of every pair,
+// request of each insertion point carry all pairs of that insertion point
+func PairGenerators(req libs.Request, sign libs.Signature, options libs.Options) []libs.Request {
+	// insertion point is the generator and position of the request in its output
+	var keys [][2]int
+	insertions := make(map[[2]int][]libs.PairRequest)
+	for _, pair := range sign.Pairs {
+		falseGroups := generatedRequests(req, pair.False, options)
+		trueGroups := generatedRequests(req, pair.True, options)
+		if len(trueGroups) != len(falseGroups) {
+			utils.WarningF("Drop pair %q - %q: %v generators for true payload but %v for false one", pair.True, pair.False, len(trueGroups), len(falseGroups))
+			continue
+		}
+		for index, trueReqs := range trueGroups {
+			falseReqs := falseGroups[index]
+			if len(trueReqs) != len(falseReqs) {
+				utils.WarningF("Drop pair %q - %q of generator %v: %v true requests but %v false ones", pair.True, pair.False, index, len(trueReqs), len(falseReqs))
 				continue
 			}
-
-			for _, injectedReq := range injectedReqs {
-				injectedReq.Target["InjectedURL"] = injectedReq.URL
-				utils.DebugF("Injected URL: %v", injectedReq.URL)
-				injectedReq.Payload = payload
-				// resolve detection this time because we may need parse something in the variable and original
-				injectedReq.Middlewares = AltResolveDetection(fuzzReq.Middlewares, injectedReq.Target)
-				injectedReq.Detections = AltResolveDetection(fuzzReq.Detections, injectedReq.Target)
-				injectedReq.Conclusions = AltResolveDetection(fuzzReq.Conclusions, injectedReq.Target)
-				reqs = append(reqs, injectedReq)
+			for position, trueReq := range trueReqs {
+				key := [2]int{index, position}
+				if _, exist := insertions[key]; !exist {
+					keys = append(keys, key)
+				}
+				insertions[key] = append(insertions[key], libs.PairRequest{True: trueReq, False: falseReqs[position]})
 			}
 		}
 	}
 
+	var reqs []libs.Request
+	for _, key := range keys {
+		pairReq := insertions[key][0].True
+		pairReq.Pairs = insertions[key]
+		reqs = append(reqs, pairReq)
+	}
 	return reqs
 }
 
+// PayloadRequests gen requests of a payload with generators of the request
+func PayloadRequests(req libs.Request, payload string, options libs.Options) []libs.Request {
+	var reqs []libs.Request
+	for _, generated := range generatedRequests(req, payload, options) {
+		reqs = append(reqs, generated...)
+	}
+	return reqs
+}
+
+// generatedRequests gen requests of a payload grouped by generator in order
+func generatedRequests(req libs.Request, payload string, options libs.Options) [][]libs.Request {
+	var groups [][]libs.Request
+	fuzzReq := req
+	// prepare something so we can access variable in generator string too
+	payload = ResolveVariable(payload, fuzzReq.Target)
+	fuzzReq.Target["payload"] = payload
+	// set original to blank first
+	fuzzReq.Target["original"] = ""
+	if fuzzReq.Engine == "raw" {
+		fuzzReq.Raw = ResolveVariable(fuzzReq.Raw, fuzzReq.Target)
+	}
+	fuzzReq.Detections = ResolveDetection(fuzzReq.Detections, fuzzReq.Target)
+	//fuzzReq.Middlewares = ResolveDetection(fuzzReq.Middlewares, fuzzReq.Target)
+	fuzzReq.Generators = funk.UniqString(ResolveDetection(fuzzReq.Generators, fuzzReq.Target))
+
+	// in case we want to send normal request with no generator
+	if len(fuzzReq.Generators) == 0 && fuzzReq.Method != "" {
+		groups = append(groups, []libs.Request{fuzzReq})
+	}
+
+	// really gen requests
+	for _, genString := range fuzzReq.Generators {
+		// just copy exactly request again
+		if genString == "Null()" {
+			groups = append(groups, []libs.Request{fuzzReq})
+			continue
+		}
+		if fuzzReq.Method == "" {
+			fuzzReq.Method = "GET"
+		}
+
+		utils.DebugF("[Generator] %v", genString)
+		injectedReqs := RunGenerator(fuzzReq, genString, options)
+		if len(injectedReqs) <= 0 {
+			utils.DebugF("No request generated by: %v", genString)
+		}
+
+		var reqs []libs.Request
+		for _, injectedReq := range injectedReqs {
+			injectedReq.Target["InjectedURL"] = injectedReq.URL
+			utils.DebugF("Injected URL: %v", injectedReq.URL)
+			injectedReq.Payload = payload
+			// resolve detection this time because we may need parse something in the variable and original
+			injectedReq.Middlewares = AltResolveDetection(fuzzReq.Middlewares, injectedReq.Target)
+			injectedReq.Detections = AltResolveDetection(fuzzReq.Detections, injectedReq.Target)
+			injectedReq.Conclusions = AltResolveDetection(fuzzReq.Conclusions, injectedReq.Target)
+			reqs = append(reqs, injectedReq)
+		}
+		// keep empty group so generators of true and false payload stay in the same order
+		groups = append(groups, reqs)
+	}
+
+	return groups
+}
+
 // RunGenerator is main function for generator
-func RunGenerator(req libs.Request, genString string) []libs.Request {
+func RunGenerator(req libs.Request, genString string, options libs.Options) []libs.Request {
+	rec := &Record{Request: req, Opt: options}
+	generator := generatorVMs.Get(rec)
+	defer generatorVMs.Put(generator)
+
+	generator.Run(genString, scriptTimeout(rec))
+	return generator.requests()
+}
+
+// newGeneratorVM register generator functions, they work on the request of the record bound to the VM
+func newGeneratorVM() *scriptVM {
+	var req libs.Request
 	var reqs []libs.Request
 	vm := otto.New()
 
@@ -197,8 +271,35 @@ func RunGenerator(req libs.Request, genString string) []libs.Request {
 		return otto.Value{}
 	})
 
-	vm.Run(genString)
-	return reqs
+	vm.Set("MethodOverride", func(call otto.FunctionCall) otto.Value {
+		if len(reqs) > 0 {
+			for _, req := range reqs {
+				injectedReq := MethodOverride(req, call.ArgumentList)
+				reqs = append(reqs, injectedReq...)
+			}
+		} else {
+			injectedReq := MethodOverride(req, call.ArgumentList)
+			reqs = append(reqs, injectedReq...)
+		}
+		return otto.Value{}
+	})
+
+	return &scriptVM{
+		vm: vm,
+		bind: func(rec *Record) {
+			req = libs.Request{}
+			if rec != nil {
+				req = rec.Request
+			}
+			reqs = nil
+		},
+		output: func() string {
+			return ""
+		},
+		requests: func() []libs.Request {
+			return reqs
+		},
+	}
 }
 
 // Encoder encoding part after resolve
@@ -220,19 +321,88 @@ func Encoder(encodeString string, data string) string {
 }
 
 // Method gen request with multiple method
+// Usage: Method(), Method('PROPFIND'), Method('MKCOL', 'DEBUG'), Method('TRACE,FOO')
 func Method(req libs.Request, arguments []otto.Value) []libs.Request {
 	methods := []string{"GET", "POST", "PUT", "HEAD", "PATCH"}
 	if len(arguments) > 0 {
-		methods = []string{strings.ToUpper(arguments[0].String())}
+		methods = []string{}
+		for _, argument := range arguments {
+			for _, method := range strings.Split(argument.String(), ",") {
+				if method = strings.TrimSpace(method); method != "" {
+					methods = append(methods, method)
+				}
+			}
+		}
 	}
+	target := req.Target
+	if target == nil {
+		target = make(map[string]string)
+	}
+	target["original"] = req.Method
+
 	var reqs []libs.Request
 	for _, method := range methods {
 		injectedReq := req
 		injectedReq.Method = method
-		injectedReq.Target["original"] = req.Method
+		injectedReq.Target = target
+		reqs = append(reqs, injectedReq)
+	}
+
+	return reqs
+}
+
+// methodOverrideHeaders common headers to tunnel a method through another one
+var methodOverrideHeaders = []string{
+	"X-HTTP-Method-Override",
+	"X-HTTP-Method",
+	"X-Method-Override",
+}
+
+// MethodOverride gen request that tunnel the method via override headers and _method param
+// Usage: MethodOverride('PUT'), MethodOverride('DELETE', 'GET')
+func MethodOverride(req libs.Request, arguments []otto.Value) []libs.Request {
+	var reqs []libs.Request
+	if len(arguments) == 0 {
+		return reqs
+	}
+	overrideMethod := strings.TrimSpace(arguments[0].String())
+	method := "POST"
+	if len(arguments) > 1 {
+		method = strings.TrimSpace(arguments[1].String())
+	}
+	target := req.Target
+	if target == nil {
+		target = make(map[string]string)
+	}
+	target["original"] = req.Method
+
+	for _, headerName := range methodOverrideHeaders {
+		injectedReq := req
+		injectedReq.Method = method
+		injectedReq.Target = target
+		var newHeaders []map[string]string
+		for _, header := range req.Headers {
+			if !funk.Contains(header, headerName) {
+				newHeaders = append(newHeaders, header)
+			}
+		}
+		newHeaders = append(newHeaders, map[string]string{headerName: overrideMethod})
+		injectedReq.Headers = newHeaders
 		reqs = append(reqs, injectedReq)
 	}
 
+	// _method param for frameworks like Rails or Laravel
+	u, err := url.Parse(req.URL)
+	if err == nil {
+		injectedReq := req
+		injectedReq.Method = method
+		injectedReq.Target = target
+		query := u.Query()
+		query.Set("_method", overrideMethod)
+		u.RawQuery = query.Encode()
+		injectedReq.URL = u.String()
+		reqs = append(reqs, injectedReq)
+	}
 	return reqs
 }
 
@@ -268,7 +438,15 @@ func Query(req libs.Request, arguments []otto.Value) []libs.Request {
 		return reqs
 	}
 
-	for key, value := range u.Query() {
+	// params in order so requests of different payloads line up
+	params := u.Query()
+	var keys []string
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := params[key]
 		injectedReq := req
 		uu, _ := url.Parse(injectedReq.URL)
 		if len(value) == 1 {
@@ -315,7 +493,14 @@ func Body(req libs.Request, arguments []otto.Value) []libs.Request {
 		// @TODO: inject for all child node, only 3 depth for now
 		if utils.IsJSON(rawBody) {
 			jsonParsed, _ := gabs.ParseJSON([]byte(rawBody))
-			for key, child := range jsonParsed.ChildrenMap() {
+			children := jsonParsed.ChildrenMap()
+			var keys []string
+			for key := range children {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				child := children[key]
 				injectedReq := req
 				if len(child.Children()) == 0 {
 					str := fmt.Sprint(child)
@@ -645,9 +830,12 @@ func Header(req libs.Request, arguments []otto.Value) []libs.Request {
 		headerNames = append(headerNames, arguments[1].String())
 	} else {
 		for _, header := range req.Headers {
+			var keys []string
 			for key := range header {
-				headerNames = append(headerNames, key)
+				keys = append(keys, key)
 			}
+			sort.Strings(keys)
+			headerNames = append(headerNames, keys...)
 		}
 	}
 	if len(headerNames) == 0 {
@@ -725,6 +913,9 @@ func Fuzz(req libs.Request, arguments []otto.Value) []libs.Request {
 	if req.Body != "" {
 		injectedReq.Body = AltResolveVariable(req.Body, target)
 	}
+	if req.Raw != "" {
+		injectedReq.Raw = AltResolveVariable(req.Raw, target)
+	}
 
 	if len(req.Headers) == 0 {
 		reqs = append(reqs, injectedReq)
@@ -757,6 +948,9 @@ func ReplaceMe(req libs.Request, arguments []otto.Value) []libs.Request {
 		injectedReq.Body = strings.Replace(req.Body, replaceWord, injectedString, -1)
 		utils.DebugF("Injected body: %v", injectedReq.Body)
 	}
+	if req.Raw != "" {
+		injectedReq.Raw = strings.Replace(req.Raw, replaceWord, injectedString, -1)
+	}
 	if len(req.Headers) == 0 {
 		reqs = append(reqs, injectedReq)
 		return reqs
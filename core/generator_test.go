@@ -1,6 +1,8 @@
 package core
 
 import (
+	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/jaeles-project/jaeles/libs"
@@ -22,10 +24,76 @@ import (
 func TestGeneratorMethod(t *testing.T) {
 	var req libs.Request
 	req.Method = "GET"
-	reqs := RunGenerator(req, `Method("PUT")`)
+	reqs := RunGenerator(req, `Method("PUT")`, libs.Options{})
 	for _, r := range reqs {
 		if r.Method != "PUT" {
 			t.Errorf("Error generate Path")
 		}
 	}
 }
+
+func TestGeneratorMethodOverride(t *testing.T) {
+	var req libs.Request
+	req.Method = "GET"
+	req.URL = "http://example.com/api/users/1?id=1"
+	reqs := RunGenerator(req, `MethodOverride("DELETE")`, libs.Options{})
+	if len(reqs) != 4 {
+		t.Fatalf("Expect 3 override headers and _method param, got %v", len(reqs))
+	}
+	for _, r := range reqs {
+		if r.Method != "POST" || r.Target["original"] != "GET" {
+			t.Errorf("Error generate MethodOverride: %v %v", r.Method, r.Target["original"])
+		}
+	}
+	if !strings.Contains(reqs[3].URL, "_method=DELETE") {
+		t.Errorf("Error generate _method param: %v", reqs[3].URL)
+	}
+	if reqs[0].Headers[0]["X-HTTP-Method-Override"] != "DELETE" {
+		t.Errorf("Error generate override header: %v", reqs[0].Headers)
+	}
+}
+
+func TestPairGenerators(t *testing.T) {
+	var req libs.Request
+	req.Method = "GET"
+	req.URL = "http://example.com/1?id=1&port=1&name=a"
+	req.Target = ParseTarget(req.URL)
+	req.Generators = []string{`Query("{{.payload}}")`, `Path("{{.payload}}")`}
+	var sign libs.Signature
+	// short payloads also in other params and the path
+	sign.Pairs = []libs.Pair{{True: "1", False: "2"}, {True: "3", False: "4"}}
+
+	// part of the URL differ between true and false request
+	changed := func(trueURL string, falseURL string) string {
+		trueU, _ := url.Parse(trueURL)
+		falseU, _ := url.Parse(falseURL)
+		var parts []string
+		if trueU.Path != falseU.Path {
+			parts = append(parts, "path")
+		}
+		for key := range trueU.Query() {
+			if trueU.Query().Get(key) != falseU.Query().Get(key) {
+				parts = append(parts, key)
+			}
+		}
+		return strings.Join(parts, ",")
+	}
+
+	reqs := PairGenerators(req, sign, libs.Options{})
+	if len(reqs) != 4 {
+		t.Fatalf("Expect 3 params and the path, got %v", len(reqs))
+	}
+	seen := make(map[string]bool)
+	for _, r := range reqs {
+		if len(r.Pairs) != 2 {
+			t.Errorf("Expect all pairs of %v, got %v", r.URL, len(r.Pairs))
+			continue
+		}
+		first := changed(r.Pairs[0].True.URL, r.Pairs[0].False.URL)
+		second := changed(r.Pairs[1].True.URL, r.Pairs[1].False.URL)
+		if first == "" || strings.Contains(first, ",") || first != second || seen[first] {
+			t.Errorf("Expect pairs at the same insertion point, got %v and %v", first, second)
+		}
+		seen[first] = true
+	}
+}
@@ -21,9 +21,22 @@ import (
 
 // Conclude is main function for detections
 func (r *Record) MiddleWare() {
-	//record := *r
-	vm := otto.New()
+	middleware := middlewareVMs.Get(r)
+	defer middlewareVMs.Put(middleware)
+	timeout := scriptTimeout(r)
+
+	for _, middleScript := range r.Request.Middlewares {
+		utils.DebugF("[MiddleWare]: %s", middleScript)
+		middleware.Run(middleScript, timeout)
+	}
+	r.Request.MiddlewareOutput = middleware.output()
+}
+
+// newMiddlewareVM register middleware functions, they work on the record bound to the VM
+func newMiddlewareVM() *scriptVM {
+	var r *Record
 	var middlewareOutput string
+	vm := otto.New()
 
 	vm.Set("Host2IP", func(call otto.FunctionCall) otto.Value {
 		var realHeaders []map[string]string
@@ -57,11 +70,16 @@ func (r *Record) MiddleWare() {
 		return otto.Value{}
 	})
 
-	for _, middleScript := range r.Request.Middlewares {
-		utils.DebugF("[MiddleWare]: %s", middleScript)
-		vm.Run(middleScript)
+	return &scriptVM{
+		vm: vm,
+		bind: func(rec *Record) {
+			r = rec
+			middlewareOutput = ""
+		},
+		output: func() string {
+			return middlewareOutput
+		},
 	}
-	r.Request.MiddlewareOutput = middlewareOutput
 }
 
 //
@@ -0,0 +1,65 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jaeles-project/jaeles/libs"
+	"github.com/jaeles-project/jaeles/sender"
+	"github.com/jaeles-project/jaeles/utils"
+)
+
+// InitNetworkRunner init task
+func InitNetworkRunner(url string, sign libs.Signature, opt libs.Options) (Runner, error) {
+	var runner Runner
+	runner.Input = url
+	runner.Opt = opt
+	runner.Sign = sign
+	runner.RunnerType = "network"
+	runner.PrepareTarget()
+	return runner, nil
+}
+
+// Connecting send network part and do detections
+func (r *Runner) Connecting() {
+	for _, nw := range r.Sign.Network {
+		// default to host and port of the input
+		if nw.Host == "" {
+			nw.Host = "{{.Domain}}"
+		}
+		if nw.Port == "" {
+			nw.Port = "{{.Port}}"
+		}
+		nw.Host = ResolveVariable(nw.Host, r.Target)
+		nw.Port = ResolveVariable(nw.Port, r.Target)
+		nw.Data = ResolveVariable(nw.Data, r.Target)
+		nw.Delimiter = ResolveVariable(nw.Delimiter, r.Target)
+
+		url := sender.NetworkURL(nw)
+		release := sender.AcquireHost(r.Opt, url)
+		sender.WaitHost(r.Opt, url)
+		res, err := sender.SendNetwork(r.Opt, nw)
+		sender.ThrottleHost(r.Opt, url, res)
+		release()
+		if err != nil {
+			continue
+		}
+
+		var rec Record
+		rec.Request.URL = url
+		rec.Request.Beautify = strings.TrimSpace(fmt.Sprintf("%v\n\n%v", rec.Request.URL, nw.Data))
+		rec.Request.Target = r.Target
+		rec.Request.Detections = ResolveDetection(nw.Detections, r.Target)
+		rec.Request.PostRun = ResolveDetection(nw.PostRun, r.Target)
+		rec.Request.SignID = r.Sign.ID
+		rec.Response = res
+		rec.Sign = r.Sign
+		rec.Opt = r.Opt
+		r.Records = append(r.Records, rec)
+	}
+
+	for _, rec := range r.Records {
+		utils.DebugF("[Network] %v %v bytes", rec.Request.URL, rec.Response.Length)
+		rec.Analyze()
+	}
+}
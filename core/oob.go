@@ -0,0 +1,269 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jaeles-project/jaeles/database"
+	"github.com/jaeles-project/jaeles/libs"
+	"github.com/jaeles-project/jaeles/oob"
+	"github.com/jaeles-project/jaeles/sender"
+	"github.com/jaeles-project/jaeles/utils"
+)
+
+var (
+	oobMu sync.Mutex
+	// requests carrying OOB token that got no interaction yet, oldest first in order
+	oobPending      = make(map[string]Record)
+	oobPendingOrder []string
+	// IDs already reported, oldest first in order
+	oobReported      = make(map[string]bool)
+	oobReportedOrder []string
+	// limit of pending requests and reported IDs kept in memory, oldest ones are dropped first
+	maxPendingOOB  = oob.DefaultMaxInteractions
+	maxReportedOOB = oob.DefaultMaxInteractions
+
+	// only one poll at a time so the cursor moves forward in order
+	oobPollMu sync.Mutex
+	// sequence of the last interaction fetched from OOB server
+	oobCursor int64
+	// interactions fetched before their request was tracked, oldest first
+	oobUnmatched []oob.Interaction
+)
+
+// OOBHit wait for interactions of the request, return them as text or blank if nothing
+func OOBHit(record Record, protocol string) string {
+	if record.Request.OOB == "" || record.Opt.OOBHost == "" {
+		utils.DebugF("OOBHit need --oob-host and {{.oob}} in the request")
+		return ""
+	}
+	wait := record.Opt.OOBWait
+	if wait <= 0 {
+		wait = 5
+	}
+
+	interactions, err := newOOBClient(record.Opt).Wait(record.Request.OOB, protocol, time.Duration(wait)*time.Second)
+	if err != nil {
+		utils.ErrorF("Error polling OOB server: %v", err)
+		return ""
+	}
+	if len(interactions) == 0 {
+		return ""
+	}
+	// found by detection so background don't need to report it again
+	oobMu.Lock()
+	delete(oobPending, record.Request.OOB)
+	markReportedOOB(record.Request.OOB)
+	oobMu.Unlock()
+	return FormatInteractions(interactions)
+}
+
+// FreshOOB replace interaction ID of the request with a new one
+func FreshOOB(req *libs.Request) {
+	old := req.OOB
+	if old == "" {
+		return
+	}
+	id := oob.NewID()
+	replace := func(value string) string {
+		return strings.ReplaceAll(value, old, id)
+	}
+	replaceAll := func(values []string) []string {
+		var results []string
+		for _, value := range values {
+			results = append(results, replace(value))
+		}
+		return results
+	}
+	replaceHeaders := func(headers []map[string]string) []map[string]string {
+		var results []map[string]string
+		for _, header := range headers {
+			element := make(map[string]string)
+			for k, v := range header {
+				element[replace(k)] = replace(v)
+			}
+			results = append(results, element)
+		}
+		return results
+	}
+
+	req.OOB = id
+	req.URL = replace(req.URL)
+	req.Body = replace(req.Body)
+	req.Raw = replace(req.Raw)
+	req.Messages = replaceAll(req.Messages)
+	req.Headers = replaceHeaders(req.Headers)
+	req.Detections = replaceAll(req.Detections)
+	req.Middlewares = replaceAll(req.Middlewares)
+	req.Conclusions = replaceAll(req.Conclusions)
+	var pairs []libs.PairRequest
+	for _, pair := range req.Pairs {
+		pair.True.URL, pair.False.URL = replace(pair.True.URL), replace(pair.False.URL)
+		pair.True.Body, pair.False.Body = replace(pair.True.Body), replace(pair.False.Body)
+		pair.True.Headers, pair.False.Headers = replaceHeaders(pair.True.Headers), replaceHeaders(pair.False.Headers)
+		pair.True.OOB, pair.False.OOB = id, id
+		pairs = append(pairs, pair)
+	}
+	req.Pairs = pairs
+}
+
+// TrackOOB keep request carrying OOB token so late interactions can be reported,
+// called once detections are done so background poll doesn't take interactions OOBHit is waiting for
+func TrackOOB(rec Record) {
+	id := rec.Request.OOB
+	if id == "" || rec.Opt.OOBHost == "" {
+		return
+	}
+	raw := rec.Request.Raw + sender.BeautifyRequest(rec.Request) + strings.Join(rec.Request.Messages, "\n")
+	if !strings.Contains(strings.ToLower(raw), id) {
+		return
+	}
+
+	oobMu.Lock()
+	if !oobReported[id] {
+		if _, ok := oobPending[id]; !ok {
+			oobPendingOrder = append(oobPendingOrder, id)
+		}
+		oobPending[id] = rec
+		trimPendingOOB()
+	}
+	oobMu.Unlock()
+
+	if !rec.Opt.NoDB && database.DB != nil {
+		database.ImportReqLog(libs.Record{
+			Request:  rec.Request,
+			Response: rec.Response,
+			ScanID:   rec.Opt.ScanID,
+		}, id)
+	}
+}
+
+// PollOOB report requests that got interactions after their detections were done
+func PollOOB(options libs.Options) {
+	if options.OOBHost == "" || PendingOOB() == 0 {
+		return
+	}
+	oobPollMu.Lock()
+	defer oobPollMu.Unlock()
+	interactions, err := newOOBClient(options).Since(oobCursor)
+	if err != nil {
+		utils.ErrorF("Error polling OOB server: %v", err)
+		return
+	}
+	if len(interactions) > 0 {
+		oobCursor = interactions[len(interactions)-1].Seq
+	}
+
+	var found []Record
+	oobMu.Lock()
+	grouped := make(map[string][]oob.Interaction)
+	var unmatched []oob.Interaction
+	for _, interaction := range append(oobUnmatched, interactions...) {
+		if oobReported[interaction.ID] {
+			continue
+		}
+		if _, ok := oobPending[interaction.ID]; !ok {
+			unmatched = append(unmatched, interaction)
+			continue
+		}
+		grouped[interaction.ID] = append(grouped[interaction.ID], interaction)
+	}
+	for id, matched := range grouped {
+		rec := oobPending[id]
+		rec.ExtraOutput = FormatInteractions(matched)
+		found = append(found, rec)
+		delete(oobPending, id)
+		markReportedOOB(id)
+	}
+	// keep the newest ones in case their requests are tracked later
+	if len(unmatched) > oob.DefaultMaxInteractions {
+		unmatched = unmatched[len(unmatched)-oob.DefaultMaxInteractions:]
+	}
+	oobUnmatched = unmatched
+	oobMu.Unlock()
+
+	for _, rec := range found {
+		rec.IsVulnerable = true
+		rec.DetectString = "OOBHit() - late interaction"
+		rec.DetectResult = rec.ExtraOutput
+		rec.Output()
+		if !rec.Opt.NoDB && database.DB != nil {
+			database.UpdateReqLog(rec.Request.OOB, rec.ExtraOutput)
+		}
+	}
+}
+
+// WaitOOB linger for late interactions before exiting
+func WaitOOB(options libs.Options) {
+	pending := PendingOOB()
+	if options.OOBHost == "" || options.OOBGrace <= 0 || pending == 0 {
+		return
+	}
+	utils.InforF("Waiting %v seconds for late OOB interactions of %v requests", options.OOBGrace, pending)
+	deadline := time.Now().Add(time.Duration(options.OOBGrace) * time.Second)
+	for time.Now().Before(deadline) && PendingOOB() > 0 {
+		PollOOB(options)
+		time.Sleep(time.Second)
+	}
+	PollOOB(options)
+}
+
+// trimPendingOOB drop the oldest pending requests over the limit, must hold oobMu
+func trimPendingOOB() {
+	for len(oobPending) > maxPendingOOB && len(oobPendingOrder) > 0 {
+		oldest := oobPendingOrder[0]
+		oobPendingOrder = oobPendingOrder[1:]
+		if _, ok := oobPending[oldest]; ok {
+			utils.DebugF("Too many requests waiting for OOB interactions, drop: %v", oldest)
+			delete(oobPending, oldest)
+		}
+	}
+	// forget IDs that got their interactions
+	if len(oobPendingOrder) > 2*maxPendingOOB {
+		var order []string
+		for _, id := range oobPendingOrder {
+			if _, ok := oobPending[id]; ok {
+				order = append(order, id)
+			}
+		}
+		oobPendingOrder = order
+	}
+}
+
+// markReportedOOB remember the ID was reported and forget the oldest ones over the limit, must hold oobMu
+func markReportedOOB(id string) {
+	if !oobReported[id] {
+		oobReportedOrder = append(oobReportedOrder, id)
+	}
+	oobReported[id] = true
+	for len(oobReportedOrder) > maxReportedOOB {
+		delete(oobReported, oobReportedOrder[0])
+		oobReportedOrder = oobReportedOrder[1:]
+	}
+}
+
+// PendingOOB number of requests still waiting for interactions
+func PendingOOB() int {
+	oobMu.Lock()
+	defer oobMu.Unlock()
+	return len(oobPending)
+}
+
+// FormatInteractions turn interactions to text for output
+func FormatInteractions(interactions []oob.Interaction) string {
+	var result []string
+	for _, interaction := range interactions {
+		result = append(result, fmt.Sprintf("[%v] %v - %v\n%v", strings.ToUpper(interaction.Protocol), interaction.RemoteAddr, interaction.Time.Format(time.RFC3339), interaction.Data))
+	}
+	return strings.Join(result, "\n")
+}
+
+func newOOBClient(options libs.Options) *oob.Client {
+	server := options.OOBServer
+	if server == "" {
+		server = options.OOBHost
+	}
+	return oob.NewClient(server, options.OOBSecret)
+}
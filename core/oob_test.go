@@ -0,0 +1,248 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jaeles-project/jaeles/libs"
+	"github.com/jaeles-project/jaeles/oob"
+	"github.com/jaeles-project/jaeles/utils"
+)
+
+func TestOOBHit(t *testing.T) {
+	oobServer := httptest.NewServer(oob.NewServer("", ""))
+	defer oobServer.Close()
+	// vulnerable target fetch any URL we give it
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fetch := r.URL.Query().Get("url"); fetch != "" {
+			http.Get(fetch)
+		}
+	}))
+	defer target.Close()
+
+	opt := libs.Options{NoOutput: true, Timeout: 5, OOBWait: 2}
+	opt.OOBHost = strings.TrimPrefix(oobServer.URL, "http://")
+	sign := libs.Signature{ID: "ssrf-oob", Target: ParseTarget(target.URL)}
+	reqs := ParseRequest(libs.Request{
+		Method:     "GET",
+		URL:        "{{.BaseURL}}/?url=http://{{.oob}}",
+		Detections: []string{`OOBHit("http")`},
+	}, sign, opt)
+	if len(reqs) != 1 || reqs[0].OOB == "" || !strings.Contains(reqs[0].URL, reqs[0].OOB) {
+		t.Fatalf("Expect {{.oob}} resolved with interaction ID, got %v", reqs)
+	}
+
+	var rec Record
+	rec.Request = reqs[0]
+	rec.Sign = sign
+	rec.Opt = opt
+	rec.Response = SendRequest(opt, &rec.Request)
+	rec.Detector()
+	if !rec.IsVulnerable || !strings.Contains(rec.ExtraOutput, rec.Request.OOB) {
+		t.Errorf("Expect OOB interaction detected, got %v", rec.ExtraOutput)
+	}
+
+	// another request never call back
+	rec.Request.OOB = oob.NewID()
+	rec.Opt.OOBWait = 1
+	rec.Detector()
+	if rec.IsVulnerable {
+		t.Errorf("Expect no interaction for new ID")
+	}
+}
+
+func TestLateOOB(t *testing.T) {
+	oobServer := httptest.NewServer(oob.NewServer("", ""))
+	defer oobServer.Close()
+
+	dir := t.TempDir()
+	opt := libs.Options{NoDB: true, OOBGrace: 5, Output: dir, SummaryOutput: path.Join(dir, "jaeles-summary.txt"), SummaryVuln: path.Join(dir, "vuln.txt")}
+	opt.OOBHost = strings.TrimPrefix(oobServer.URL, "http://")
+	sign := libs.Signature{ID: "blind-xxe", Target: ParseTarget("http://example.com")}
+	sign.Info.Risk = "High"
+	reqs := ParseRequest(libs.Request{Method: "POST", URL: "{{.BaseURL}}/xml", Body: `<!ENTITY x SYSTEM "http://{{.oob}}">`}, sign, opt)
+	// request without the token shouldn't be tracked
+	unused := ParseRequest(libs.Request{Method: "GET", URL: "{{.BaseURL}}/"}, sign, opt)
+
+	for _, req := range append(reqs, unused...) {
+		var rec Record
+		rec.Request = req
+		rec.Sign = sign
+		rec.Opt = opt
+		TrackOOB(rec)
+	}
+	if PendingOOB() != 1 {
+		t.Fatalf("Expect one tracked request, got %v", PendingOOB())
+	}
+
+	// target call back long after the signature is done
+	go func() {
+		time.Sleep(1500 * time.Millisecond)
+		http.Get(oobServer.URL + "/" + reqs[0].OOB)
+	}()
+	WaitOOB(opt)
+	if PendingOOB() != 0 {
+		t.Errorf("Expect late interaction to be reported")
+	}
+	summary := utils.GetFileContent(opt.SummaryOutput)
+	if !strings.Contains(summary, "blind-xxe") {
+		t.Errorf("Expect finding in summary, got %v", summary)
+	}
+	// interaction come before its request is tracked
+	early := ParseRequest(libs.Request{Method: "POST", URL: "{{.BaseURL}}/xml", Body: `<!ENTITY x SYSTEM "http://{{.oob}}">`}, sign, opt)
+	http.Get(oobServer.URL + "/" + early[0].OOB)
+	PollOOB(opt)
+	var rec Record
+	rec.Request = early[0]
+	rec.Sign = sign
+	rec.Opt = opt
+	TrackOOB(rec)
+	PollOOB(opt)
+	if PendingOOB() != 0 {
+		t.Errorf("Expect interaction fetched before tracking to be reported")
+	}
+}
+
+func TestOOBPerRequest(t *testing.T) {
+	signContent := `
+id: ssrf-fuzz-01
+type: fuzz
+info:
+  name: SSRF fuzz test
+
+payloads:
+  - http://{{.oob}}
+
+requests:
+  - generators:
+      - Query("{{.payload}}")
+    repeat: 2
+    detections:
+      - >-
+        OOBHit()
+`
+	sign, err := ParseSignFromContent(signContent)
+	if err != nil {
+		t.Fatalf("Error parsing signature")
+	}
+	opt := libs.Options{NoDB: true, NoOutput: true, OOBHost: "oob.example.com"}
+	runner, err := InitRunner("http://example.com/?url=a&next=b", sign, opt)
+	if err != nil {
+		t.Fatalf("Error init runner")
+	}
+	// 2 insertion points and each of them repeated
+	if len(runner.Records) != 4 {
+		t.Fatalf("Expect 4 requests, got %v", len(runner.Records))
+	}
+	seen := make(map[string]bool)
+	for _, rec := range runner.Records {
+		id := rec.Request.OOB
+		if seen[id] || !strings.Contains(rec.Request.URL, id) {
+			t.Errorf("Expect unique interaction ID in %v, got %v", rec.Request.URL, id)
+		}
+		if ids := oob.ExtractIDs(rec.Request.URL); len(ids) != 1 {
+			t.Errorf("Expect only ID of the request in %v", rec.Request.URL)
+		}
+		seen[id] = true
+	}
+}
+
+func TestPendingOOBBounded(t *testing.T) {
+	defer func(max int) { maxPendingOOB = max }(maxPendingOOB)
+	maxPendingOOB = 3
+
+	var ids []string
+	for i := 0; i < 5; i++ {
+		var rec Record
+		rec.Opt = libs.Options{NoDB: true, OOBHost: "oob.example.com"}
+		rec.Request.OOB = oob.NewID()
+		rec.Request.URL = "http://example.com/?url=http://" + rec.Request.OOB + ".oob.example.com"
+		TrackOOB(rec)
+		ids = append(ids, rec.Request.OOB)
+	}
+	defer func() {
+		oobMu.Lock()
+		for _, id := range ids {
+			delete(oobPending, id)
+		}
+		oobMu.Unlock()
+	}()
+
+	oobMu.Lock()
+	_, oldest := oobPending[ids[0]]
+	_, newest := oobPending[ids[4]]
+	oobMu.Unlock()
+	if PendingOOB() > 3 || oldest || !newest {
+		t.Errorf("Expect only the newest pending requests kept, got %v", PendingOOB())
+	}
+}
+
+func TestReportedOOBBounded(t *testing.T) {
+	defer func(max int) { maxReportedOOB = max }(maxReportedOOB)
+	maxReportedOOB = 3
+
+	var ids []string
+	oobMu.Lock()
+	for i := 0; i < 5; i++ {
+		ids = append(ids, oob.NewID())
+		markReportedOOB(ids[i])
+	}
+	oldest, newest := oobReported[ids[0]], oobReported[ids[4]]
+	count := len(oobReported)
+	for _, id := range ids {
+		delete(oobReported, id)
+	}
+	oobReportedOrder = nil
+	oobMu.Unlock()
+	if count > 3 || oldest || !newest {
+		t.Errorf("Expect only the newest reported IDs kept, got %v", count)
+	}
+}
+
+func TestOOBTrackedAfterDetection(t *testing.T) {
+	oobServer := httptest.NewServer(oob.NewServer("", ""))
+	defer oobServer.Close()
+
+	opt := libs.Options{NoDB: true, NoOutput: true, OOBWait: 1}
+	opt.OOBHost = strings.TrimPrefix(oobServer.URL, "http://")
+	sign := libs.Signature{ID: "ssrf-oob", Target: ParseTarget("http://example.com")}
+	reqs := ParseRequest(libs.Request{
+		Method:     "GET",
+		URL:        "{{.BaseURL}}/?url=http://{{.oob}}",
+		Detections: []string{`OOBHit("http")`},
+	}, sign, opt)
+	http.Get(oobServer.URL + "/" + reqs[0].OOB)
+
+	// background poll while OOBHit is waiting doesn't see the request
+	var rec Record
+	rec.Request = reqs[0]
+	rec.Sign = sign
+	rec.Opt = opt
+	rec.Response.StatusCode = 200
+	done := make(chan struct{})
+	go func() {
+		rec.Analyze()
+		close(done)
+	}()
+	for polling := true; polling; {
+		select {
+		case <-done:
+			polling = false
+		default:
+			oobMu.Lock()
+			_, pending := oobPending[reqs[0].OOB]
+			oobMu.Unlock()
+			if pending {
+				t.Fatalf("Expect request not tracked while its detection is running")
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	if !rec.IsVulnerable || PendingOOB() != 0 {
+		t.Errorf("Expect interaction found by detection only, pending %v", PendingOOB())
+	}
+}
@@ -4,10 +4,12 @@ import (
 	"fmt"
 	"github.com/fatih/color"
 	"github.com/jaeles-project/jaeles/libs"
+	"github.com/jaeles-project/jaeles/sender"
 	"github.com/jaeles-project/jaeles/utils"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/logrusorgru/aurora/v3"
 	"github.com/spf13/cast"
+	"io/ioutil"
 	"net/url"
 	"os"
 	"path"
@@ -133,6 +135,14 @@ func (r *Record) StoreOutput() {
 		content += fmt.Sprintf("\n")
 	}
 
+	if len(r.Extracted) > 0 {
+		content += fmt.Sprintf("%v\n", strings.Repeat("-", 50))
+		content += fmt.Sprintf("[Extracted]\n")
+		for _, extracted := range FormatExtracted(r.Extracted) {
+			content += fmt.Sprintf("%v\n", extracted)
+		}
+	}
+
 	content += fmt.Sprintf(">>>>%v\n", strings.Repeat("-", 50))
 	if r.Request.MiddlewareOutput == "" {
 		content += r.Request.Beautify
@@ -169,6 +179,8 @@ func (r *Record) StoreOutput() {
 			utils.ErrorF("Error Write content to: %v", p)
 		}
 	}
+	screenshot := r.StoreScreenshot(p)
+
 	// store output as JSON
 	if r.Opt.JsonOutput {
 		vulnData := libs.VulnData{
@@ -181,6 +193,8 @@ func (r *Record) StoreOutput() {
 			URL:             r.Request.URL,
 			Req:             Base64Encode(r.Request.Beautify),
 			Res:             Base64Encode(r.Response.Beautify),
+			Screenshot:      screenshot,
+			Extracted:       r.Extracted,
 		}
 		if data, err := jsoniter.MarshalToString(vulnData); err == nil {
 			content = data
@@ -192,6 +206,9 @@ func (r *Record) StoreOutput() {
 
 	// summary file
 	sum := fmt.Sprintf("%v - %v", strings.TrimSpace(head), p)
+	if len(r.Extracted) > 0 {
+		sum += fmt.Sprintf(" - %v", strings.Join(FormatExtracted(r.Extracted), ", "))
+	}
 	if r.Opt.JsonOutput {
 		vulnData := libs.VulnData{
 			SignID:          r.Sign.ID,
@@ -205,6 +222,8 @@ func (r *Record) StoreOutput() {
 			ContentLength:   cast.ToString(r.Response.Length),
 			SignatureFile:   r.Sign.RawPath,
 			OutputFile:      p,
+			Screenshot:      screenshot,
+			Extracted:       r.Extracted,
 		}
 		if data, err := jsoniter.MarshalToString(vulnData); err == nil {
 			sum = data
@@ -217,3 +236,44 @@ func (r *Record) StoreOutput() {
 	utils.AppendToContent(r.Opt.SummaryVuln, vulnSum)
 	r.RawOutput = p
 }
+
+// StoreScreenshot save screenshot of the finding next to the output file,
+// chrome engine already captured its page, other engines only load it for findings
+func (r *Record) StoreScreenshot(output string) string {
+	// always on for chrome engine
+	if (!r.Opt.Screenshot && r.Request.Engine != "chrome") || !sender.IsHTML(r.Response) {
+		return ""
+	}
+	screenshot := r.Response.Screenshot
+	if len(screenshot) == 0 && r.Request.Engine != "chrome" {
+		screenshot = r.loadScreenshot()
+	}
+	if len(screenshot) == 0 {
+		return ""
+	}
+	p := output + ".png"
+	if err := ioutil.WriteFile(p, screenshot, 0644); err != nil {
+		utils.ErrorF("Error writing screenshot to: %v", p)
+		return ""
+	}
+	return p
+}
+
+// loadScreenshot load the finding again in chrome, only for GET request
+// so the payload isn't sent twice, nothing to load in replay mode
+func (r *Record) loadScreenshot() []byte {
+	method := strings.ToUpper(r.Request.Method)
+	if method != "" && method != "GET" {
+		return nil
+	}
+	if r.Request.URL == "" || r.Request.OOB != "" || r.Opt.ReplayDir != "" {
+		return nil
+	}
+	req := r.Request
+	req.Engine = "chrome"
+	res := SendRequest(r.Opt, &req)
+	if len(res.Screenshot) == 0 {
+		utils.ErrorF("Error taking screenshot of: %v", req.URL)
+	}
+	return res.Screenshot
+}
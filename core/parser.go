@@ -14,6 +14,7 @@ import (
 	"bytes"
 
 	"github.com/jaeles-project/jaeles/libs"
+	"github.com/jaeles-project/jaeles/oob"
 	"github.com/thoas/go-funk"
 	"gopkg.in/yaml.v2"
 )
@@ -280,6 +281,9 @@ func ParseOrigin(req libs.Request, sign libs.Signature, _ libs.Options) libs.Req
 	target := sign.Target
 	// resolve some parts with global variables first
 	req.Target = target
+	if sign.FreshConn {
+		req.FreshConn = true
+	}
 	if strings.Contains(req.Method, "{{.") {
 		req.Method = ResolveVariable(req.Method, target)
 	}
@@ -289,6 +293,7 @@ func ParseOrigin(req libs.Request, sign libs.Signature, _ libs.Options) libs.Req
 		req.URL = ResolveVariable(req.Path, target)
 	}
 	req.Body = ResolveVariable(req.Body, target)
+	req.Messages = ResolveDetection(req.Messages, target)
 	req.Headers = ResolveHeader(req.Headers, target)
 	req.Middlewares = ResolveDetection(req.Middlewares, target)
 	req.Conclusions = ResolveDetection(req.Conclusions, target)
@@ -301,6 +306,9 @@ func ParseOrigin(req libs.Request, sign libs.Signature, _ libs.Options) libs.Req
 		burpReq.Detections = ResolveDetection(req.Detections, target)
 		burpReq.Middlewares = ResolveDetection(req.Middlewares, target)
 		burpReq.Conclusions = ResolveDetection(req.Conclusions, target)
+		burpReq.FreshConn = req.FreshConn
+		burpReq.TLS = req.TLS
+		burpReq.Extractors = req.Extractors
 		return burpReq
 	}
 	return req
@@ -308,11 +316,35 @@ func ParseOrigin(req libs.Request, sign libs.Signature, _ libs.Options) libs.Req
 
 // ParseRequest parse request part in YAML signature file
 func ParseRequest(req libs.Request, sign libs.Signature, options libs.Options) []libs.Request {
+	Reqs := parseRequest(req, sign, options)
+	// every generated request get its own interaction ID so each hit map to exactly one request
+	if options.OOBHost != "" {
+		for i := range Reqs {
+			FreshOOB(&Reqs[i])
+		}
+	}
+	return Reqs
+}
+
+func parseRequest(req libs.Request, sign libs.Signature, options libs.Options) []libs.Request {
 	var Reqs []libs.Request
 	target := sign.Target
+	// interaction ID as a template, replaced for each generated request
+	if options.OOBHost != "" {
+		req.OOB = oob.NewID()
+		target["oob"] = oob.Payload(options.OOBHost, req.OOB)
+		target["oob_id"] = req.OOB
+	}
+	// sleep value is set for each sample when sending in timing mode
+	if req.Timing > 0 {
+		target["delay"] = timingPlaceholder
+	}
 
 	// resolve some parts with global variables first
 	req.Target = target
+	if sign.FreshConn {
+		req.FreshConn = true
+	}
 	if strings.Contains(req.Method, "{{.") {
 		req.Method = ResolveVariable(req.Method, target)
 	}
@@ -322,6 +354,7 @@ func ParseRequest(req libs.Request, sign libs.Signature, options libs.Options) [
 		req.URL = ResolveVariable(req.Path, target)
 	}
 	req.Body = ResolveVariable(req.Body, target)
+	req.Messages = ResolveDetection(req.Messages, target)
 	req.Headers = ResolveHeader(req.Headers, target)
 	req.Proxy = ResolveVariable(req.Proxy, target)
 	req.Res = ResolveVariable(req.Res, target)
@@ -341,16 +374,36 @@ func ParseRequest(req libs.Request, sign libs.Signature, options libs.Options) [
 	req.Conclusions = ResolveDetection(req.Conclusions, target)
 	req.PostRun = ResolveDetection(req.PostRun, target)
 
+	// raw engine send the raw request as it is so don't parse it, payload is resolved later when fuzzing
+	rawEngine := req.Engine == "raw" && req.Raw != ""
+	if rawEngine {
+		if sign.Type != "fuzz" {
+			req.Raw = ResolveVariable(req.Raw, target)
+		}
+		if req.URL == "" {
+			req.URL = target["BaseURL"]
+		}
+		if fields := strings.Fields(req.Raw); len(fields) > 0 {
+			req.Method = fields[0]
+		}
+	}
+
 	if sign.Type != "fuzz" {
 		if req.Res != "" {
 			Reqs = append(Reqs, req)
 		}
 		// in case we only want to run a middleware alone
-		if req.Raw != "" {
+		if req.Raw != "" && !rawEngine {
 			rawReq := ResolveVariable(req.Raw, target)
 			burpReq := ParseBurpRequest(rawReq)
 			burpReq.Detections = ResolveDetection(req.Detections, target)
 			burpReq.Middlewares = ResolveDetection(req.Middlewares, target)
+			burpReq.FreshConn = req.FreshConn
+			burpReq.TLS = req.TLS
+			burpReq.Extractors = req.Extractors
+			burpReq.Timing = req.Timing
+			burpReq.Repeat = req.Repeat
+			burpReq.OOB = req.OOB
 			Reqs = append(Reqs, burpReq)
 		}
 
@@ -379,19 +432,23 @@ func ParseRequest(req libs.Request, sign libs.Signature, options libs.Options) [
 	var record libs.Record
 
 	// parse raw request in case we have -r options as a origin request
-	if req.Raw != "" {
+	if req.Raw != "" && !rawEngine {
 		rawReq := ResolveVariable(req.Raw, target)
 		burpReq := ParseBurpRequest(rawReq)
 		// resolve again with custom delimiter generator
 		burpReq.Generators = req.Generators
 		burpReq.Detections = req.Detections
 		burpReq.Middlewares = req.Middlewares
+		burpReq.FreshConn = req.FreshConn
+		burpReq.TLS = req.TLS
+		burpReq.OOB = req.OOB
 		record.OriginReq = burpReq
 	} else {
 		record.OriginReq.URL = target["URL"]
 	}
 
 	record.Request = req
+	record.Opt = options
 	reqs := ParseFuzzRequest(record, sign)
 	if len(reqs) > 0 {
 		Reqs = append(Reqs, reqs...)
@@ -410,8 +467,8 @@ func ParseRequest(req libs.Request, sign libs.Signature, options libs.Options) [
 	//	Reqs = realReqs
 	//}
 
-	// repeat section
-	if req.Repeat == 0 {
+	// repeat section, timing mode repeat samples when sending
+	if req.Repeat == 0 || req.Timing > 0 {
 		return Reqs
 	}
 	realReqsWithRepeat := Reqs
@@ -431,7 +488,7 @@ func ParseFuzzRequest(record libs.Record, sign libs.Signature) []libs.Request {
 	if req.URL == "" {
 		req.URL = record.OriginReq.URL
 	}
-	Reqs = Generators(req, sign)
+	Reqs = Generators(req, sign, record.Opt)
 	return Reqs
 }
 
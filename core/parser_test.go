@@ -3,6 +3,7 @@ package core
 import (
 	"fmt"
 	"github.com/davecgh/go-spew/spew"
+	"github.com/jaeles-project/jaeles/libs"
 	"strings"
 	"testing"
 )
@@ -264,3 +265,54 @@ X-Amz-Cf-Id: lQqv4XdysrJ1ODW9fUufpf2tpGHr4Sxj79BvkPmcUbRQaGChQwCBqw==
 	}
 
 }
+
+func TestParseRawEngine(t *testing.T) {
+	signContent := `
+id: raw-fuzz-01
+type: fuzz
+info:
+  name: Raw fuzz test
+
+payloads:
+  - aaa
+  - bbb
+
+requests:
+  - engine: raw
+    raw: |
+      GET /?q=FUZZ HTTP/1.1
+      Host: {{.Host}}
+
+    generators:
+      - Replace("FUZZ")
+    middlewares:
+      - >-
+        InvokeCmd("echo [[.payload]]")
+    detections:
+      - >-
+        StatusCode() == 200
+`
+	sign, err := ParseSignFromContent(signContent)
+	if err != nil {
+		t.Fatalf("Error parsing signature")
+	}
+	runner, err := InitRunner("http://example.com/", sign, libs.Options{NoDB: true, NoOutput: true})
+	if err != nil {
+		t.Fatalf("Error init runner")
+	}
+	if len(runner.Records) != 2 {
+		t.Fatalf("Expect one raw request for each payload, got %v", len(runner.Records))
+	}
+	for _, rec := range runner.Records {
+		req := rec.Request
+		if req.Engine != "raw" || req.Method != "GET" || req.URL != "http://example.com" {
+			t.Errorf("Raw request should not be parsed: %v %v %v", req.Engine, req.Method, req.URL)
+		}
+		if !strings.Contains(req.Raw, "?q="+req.Payload+" ") || !strings.Contains(req.Raw, "Host: example.com") {
+			t.Errorf("Payload not injected in raw request: %q", req.Raw)
+		}
+		if len(req.Middlewares) != 1 || !strings.Contains(req.Middlewares[0], req.Payload) {
+			t.Errorf("Middlewares of raw request not resolved: %v", req.Middlewares)
+		}
+	}
+}
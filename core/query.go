@@ -0,0 +1,129 @@
+package core
+
+import (
+	"strings"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/antchfx/htmlquery"
+	"github.com/antchfx/xmlquery"
+)
+
+// JsonQuery get the node at the JSONPath, support $.a.b, $.a[0].b, $.a[*].b and $['a']
+func JsonQuery(component string, jsonPath string) (*gabs.Container, bool) {
+	parsed, err := gabs.ParseJSON([]byte(component))
+	if err != nil {
+		return nil, false
+	}
+	node := parsed.Search(jsonPathSegments(jsonPath)...)
+	if node == nil {
+		return nil, false
+	}
+	return node, true
+}
+
+// JsonPath get value at the JSONPath, string is returned as is and other type as JSON
+func JsonPath(component string, jsonPath string) (string, bool) {
+	node, ok := JsonQuery(component, jsonPath)
+	if !ok {
+		return "", false
+	}
+	switch value := node.Data().(type) {
+	case string:
+		return value, true
+	case nil:
+		return "null", true
+	}
+	return node.String(), true
+}
+
+// JsonExist check if the JSONPath exist
+func JsonExist(component string, jsonPath string) bool {
+	_, ok := JsonQuery(component, jsonPath)
+	return ok
+}
+
+// JsonType get type of value at the JSONPath: object, array, string, number, boolean or null
+func JsonType(component string, jsonPath string) string {
+	node, ok := JsonQuery(component, jsonPath)
+	if !ok {
+		return ""
+	}
+	switch node.Data().(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	}
+	return "number"
+}
+
+// split JSONPath to segments for gabs
+func jsonPathSegments(jsonPath string) []string {
+	var segments []string
+	path := strings.TrimPrefix(strings.TrimSpace(jsonPath), "$")
+	for len(path) > 0 {
+		switch path[0] {
+		case '.':
+			path = path[1:]
+		case '[':
+			end := strings.Index(path, "]")
+			if end < 0 {
+				segments = append(segments, strings.Trim(path[1:], `'"`))
+				return segments
+			}
+			segments = append(segments, strings.Trim(path[1:end], `'"`))
+			path = path[end+1:]
+		default:
+			end := strings.IndexAny(path, ".[")
+			if end < 0 {
+				end = len(path)
+			}
+			segments = append(segments, path[:end])
+			path = path[end:]
+		}
+	}
+	return segments
+}
+
+// HtmlSelect get text of first element match CSS selector, or its attribute if attr is set
+func HtmlSelect(component string, selector string, attr string) (string, bool) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(component))
+	if err != nil {
+		return "", false
+	}
+	selection := doc.Find(selector).First()
+	if selection.Length() == 0 {
+		return "", false
+	}
+	if attr != "" {
+		return selection.Attr(attr)
+	}
+	return strings.TrimSpace(selection.Text()), true
+}
+
+// XPath get value of first node match the XPath, component is parsed as HTML when it's not well-formed XML
+func XPath(component string, xpath string) (string, bool) {
+	if doc, err := xmlquery.Parse(strings.NewReader(component)); err == nil {
+		node, err := xmlquery.Query(doc, xpath)
+		if err != nil || node == nil {
+			return "", false
+		}
+		return strings.TrimSpace(node.InnerText()), true
+	}
+	doc, err := htmlquery.Parse(strings.NewReader(component))
+	if err != nil {
+		return "", false
+	}
+	node, err := htmlquery.Query(doc, xpath)
+	if err != nil || node == nil {
+		return "", false
+	}
+	return strings.TrimSpace(htmlquery.InnerText(node)), true
+}
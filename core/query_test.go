@@ -0,0 +1,97 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/jaeles-project/jaeles/libs"
+)
+
+func TestJsonPath(t *testing.T) {
+	body := `{"data": {"user": {"role": "admin", "id": 1, "tags": ["a", "b"], "token": null}}, "items": [{"id": 7}, {"id": 8}], "x-key": true}`
+	cases := map[string]string{
+		"$.data.user.role":    "admin",
+		"$.data.user.id":      "1",
+		"$.data.user.tags[1]": "b",
+		"$.items[*].id":       "[7,8]",
+		"$['x-key']":          "true",
+		"data.user.token":     "null",
+	}
+	for path, expected := range cases {
+		if value, ok := JsonPath(body, path); !ok || value != expected {
+			t.Errorf("JsonPath %v got %v, expected %v", path, value, expected)
+		}
+	}
+	if JsonExist(body, "$.data.user.password") || !JsonExist(body, "$.data.user.token") {
+		t.Errorf("JsonExist wrong")
+	}
+	if JsonType(body, "$.data.user.tags") != "array" || JsonType(body, "$.data.user.id") != "number" {
+		t.Errorf("JsonType wrong")
+	}
+	if _, ok := JsonPath("not json", "$.a"); ok {
+		t.Errorf("Expect no value from invalid JSON")
+	}
+}
+
+func TestXPathAndHtmlSelect(t *testing.T) {
+	xml := `<users><user id="1"><role>admin</role></user><user id="2"><role>guest</role></user></users>`
+	cases := map[string]string{
+		"/users/user[2]/role":             "guest",
+		"//user[@id='1']/role":            "admin",
+		"//user[1]/@id":                   "1",
+		"//role[text()='guest']":          "guest",
+		"//user[contains(@id, '2')]/role": "guest",
+	}
+	for xpath, expected := range cases {
+		value, _ := XPath(xml, xpath)
+		if value != expected {
+			t.Errorf("XPath %v got %q, expected %q", xpath, value, expected)
+		}
+	}
+
+	// self-closing elements and names of HTML void elements
+	xml = `<feed><link href="/a"/><users><user id="1"/><user id="2"><role>admin</role></user></users></feed>`
+	cases = map[string]string{
+		"/feed/users/user[2]/role": "admin",
+		"//link/@href":             "/a",
+	}
+	for xpath, expected := range cases {
+		if value, ok := XPath(xml, xpath); !ok || value != expected {
+			t.Errorf("XPath %v got %q, expected %q", xpath, value, expected)
+		}
+	}
+	if value, _ := XPath(`<html><head><link rel="icon" href="/x.ico"><title>t</title></head></html>`, "//link/@href"); value != "/x.ico" {
+		t.Errorf("Expect XPath on HTML, got %q", value)
+	}
+
+	html := `<form id="login"><input name="csrf" value="s3cret"></form>`
+	if value, ok := HtmlSelect(html, "form#login input[name=csrf]", "value"); !ok || value != "s3cret" {
+		t.Errorf("HtmlSelect got %v", value)
+	}
+}
+
+func TestQueryInScripts(t *testing.T) {
+	var rec Record
+	rec.Opt = libs.Options{NoOutput: true}
+	rec.Request.Target = make(map[string]string)
+	rec.Response.Body = `{"data": {"user": {"role": "admin"}}}`
+	rec.Request.Detections = []string{`JsonPath("body", "$.data.user.role") == "admin"`}
+	rec.Detector()
+	if !rec.IsVulnerable || rec.ExtraOutput != "admin" {
+		t.Errorf("Expect detection match with extra output, got %v %v", rec.IsVulnerable, rec.ExtraOutput)
+	}
+
+	// value of query function isn't a boolean, it has to be compared
+	rec.Response.Body = `{"user": {"admin": "false"}}`
+	rec.Request.Detections = []string{`JsonPath("body", "$.user.admin")`}
+	rec.Detector()
+	if rec.IsVulnerable {
+		t.Errorf("Expect string value not to count as vulnerable")
+	}
+
+	rec.Response.Body = `{"data": {"user": {"role": "admin"}}}`
+	rec.Request.Conclusions = []string{`SetValue("role", JsonPath("body", "$.data.user.role"))`}
+	rec.Conclude()
+	if rec.Request.Target["role"] != "admin" {
+		t.Errorf("Expect value set from conclusion, got %v", rec.Request.Target["role"])
+	}
+}
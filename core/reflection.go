@@ -0,0 +1,419 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/jaeles-project/jaeles/libs"
+	"github.com/thoas/go-funk"
+)
+
+// contexts of reflection
+const (
+	ContextHTML              = "html"
+	ContextAttributeQuoted   = "attribute-quoted"
+	ContextAttributeUnquoted = "attribute-unquoted"
+	ContextScriptString      = "script-string"
+	ContextScriptCode        = "script-code"
+	ContextComment           = "comment"
+	ContextURL               = "url"
+	ContextJSON              = "json"
+)
+
+// attributes hold URL as value
+var urlAttributes = []string{"href", "src", "action", "formaction", "data", "poster", "background", "cite", "codebase", "xlink:href"}
+
+// Reflection a reflection of the canary in the response
+type Reflection struct {
+	Position int
+	Context  string
+	// quote wrap the reflection in quoted attribute or script string
+	Quote string
+	// special chars of the payload kept unencoded
+	Chars string
+}
+
+// String summary of the reflection
+func (reflection Reflection) String() string {
+	context := reflection.Context
+	if reflection.Quote != "" {
+		context += fmt.Sprintf("(%v)", reflection.Quote)
+	}
+	return fmt.Sprintf("%v at %v survived %q", context, reflection.Position, reflection.Chars)
+}
+
+// Reflections find every reflection of the payload in the body,
+// the longest alphanumeric part of payload is used as canary to locate them
+// and it only count when other alphanumeric parts of the payload are around it in order,
+// so words like "script" in the page's own markup aren't taken as reflection
+func Reflections(body string, payload string, isJSON bool) []Reflection {
+	canary := Canary(payload)
+	if canary == "" {
+		return nil
+	}
+	offset := strings.Index(payload, canary)
+	if !isJSON {
+		trimmed := strings.TrimSpace(body)
+		isJSON = (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(trimmed))
+	}
+
+	var reflections []Reflection
+	for start := 0; start < len(body); {
+		index := strings.Index(body[start:], canary)
+		if index < 0 {
+			break
+		}
+		position := start + index
+		start = position + len(canary)
+
+		// context is where the reflected payload start, not the canary
+		chars, payloadStart, ok := survivedChars(body, position, payload, offset, len(canary))
+		if !ok {
+			continue
+		}
+		reflection := Reflection{Position: payloadStart, Context: ContextJSON}
+		if !isJSON {
+			reflection = htmlContext(body, payloadStart)
+		}
+		reflection.Chars = chars
+		reflections = append(reflections, reflection)
+	}
+	return reflections
+}
+
+// Canary get the longest alphanumeric part of payload, whole payload is used when it's too short
+func Canary(payload string) string {
+	var canary string
+	for _, part := range strings.FieldsFunc(payload, func(c rune) bool {
+		return !unicode.IsLetter(c) && !unicode.IsDigit(c)
+	}) {
+		if len(part) > len(canary) {
+			canary = part
+		}
+	}
+	if len(canary) < 3 {
+		return payload
+	}
+	return canary
+}
+
+// states of the html scanner
+const (
+	stateText = iota
+	stateComment
+	stateTag
+	stateAttrName
+	stateBeforeValue
+	stateAttrValue
+	stateScript
+	stateScriptString
+	stateScriptLineComment
+	stateScriptBlockComment
+)
+
+// htmlContext scan the body until position to know where the reflection is
+func htmlContext(body string, position int) Reflection {
+	lower := strings.ToLower(body[:position])
+	state := stateText
+	var tagName, attrName string
+	var quote byte
+	var valueStart int
+	for i := 0; i < len(lower); i++ {
+		c := lower[i]
+		switch state {
+		case stateText:
+			if strings.HasPrefix(lower[i:], "<!--") {
+				state = stateComment
+				i += 3
+			} else if c == '<' && i+1 < len(lower) && (isLetter(lower[i+1]) || lower[i+1] == '/') {
+				end := i + 1
+				for end < len(lower) && !isSpace(lower[end]) && lower[end] != '>' {
+					end++
+				}
+				tagName = lower[i+1 : end]
+				state = stateTag
+				i = end - 1
+			}
+		case stateComment:
+			if strings.HasPrefix(lower[i:], "-->") {
+				state = stateText
+				i += 2
+			}
+		case stateTag, stateAttrName, stateBeforeValue, stateAttrValue:
+			if state == stateAttrValue && quote != 0 {
+				if c == quote {
+					state = stateTag
+				}
+				continue
+			}
+			if c == '>' {
+				state = stateText
+				if tagName == "script" {
+					state = stateScript
+				}
+				continue
+			}
+			switch state {
+			case stateTag:
+				if !isSpace(c) && c != '/' {
+					state = stateAttrName
+					attrName = string(c)
+				}
+			case stateAttrName:
+				if c == '=' {
+					state = stateBeforeValue
+				} else if isSpace(c) {
+					state = stateTag
+				} else {
+					attrName += string(c)
+				}
+			case stateBeforeValue:
+				if c == '"' || c == '\'' {
+					state, quote, valueStart = stateAttrValue, c, i+1
+				} else if !isSpace(c) {
+					state, quote, valueStart = stateAttrValue, 0, i
+				}
+			case stateAttrValue:
+				if isSpace(c) {
+					state = stateTag
+				}
+			}
+		case stateScript:
+			if strings.HasPrefix(lower[i:], "</script") {
+				state, tagName = stateTag, "/script"
+				i += len("</script") - 1
+			} else if c == '"' || c == '\'' || c == '`' {
+				state, quote = stateScriptString, c
+			} else if strings.HasPrefix(lower[i:], "//") {
+				state = stateScriptLineComment
+			} else if strings.HasPrefix(lower[i:], "/*") {
+				state = stateScriptBlockComment
+				i++
+			}
+		case stateScriptString:
+			if c == '\\' {
+				i++
+			} else if c == quote || (c == '\n' && quote != '`') {
+				state = stateScript
+			}
+		case stateScriptLineComment:
+			if c == '\n' {
+				state = stateScript
+			} else if strings.HasPrefix(lower[i:], "</script") {
+				state, tagName = stateTag, "/script"
+				i += len("</script") - 1
+			}
+		case stateScriptBlockComment:
+			if strings.HasPrefix(lower[i:], "*/") {
+				state = stateScript
+				i++
+			}
+		}
+	}
+
+	reflection := Reflection{Position: position}
+	switch state {
+	case stateComment, stateScriptLineComment, stateScriptBlockComment:
+		reflection.Context = ContextComment
+	case stateTag, stateAttrName, stateBeforeValue:
+		// inside the tag, same as unquoted value that could add new attribute
+		reflection.Context = ContextAttributeUnquoted
+	case stateAttrValue:
+		if quote != 0 {
+			reflection.Context, reflection.Quote = ContextAttributeQuoted, string(quote)
+		} else {
+			reflection.Context = ContextAttributeUnquoted
+		}
+		if strings.HasPrefix(attrName, "on") {
+			reflection.Context, reflection.Quote = ContextScriptCode, ""
+		} else if isURLAttribute(attrName, lower[valueStart:]) {
+			reflection.Context, reflection.Quote = ContextURL, ""
+		}
+	case stateScript:
+		reflection.Context = ContextScriptCode
+	case stateScriptString:
+		reflection.Context, reflection.Quote = ContextScriptString, string(quote)
+	default:
+		reflection.Context = ContextHTML
+	}
+	return reflection
+}
+
+// attribute hold URL and the reflection is before its query or fragment, so scheme can be controlled
+func isURLAttribute(attrName string, value string) bool {
+	for _, name := range urlAttributes {
+		if attrName == name {
+			return !strings.ContainsAny(value, "?#")
+		}
+	}
+	return false
+}
+
+// survivedChars walk the payload around the canary to get special chars reflected as is
+// and where the reflected payload start, special chars may be encoded or dropped
+// but alphanumeric ones have to be there or it's not a reflection of the payload
+func survivedChars(body string, position int, payload string, offset int, length int) (string, int, bool) {
+	survived := make(map[byte]bool)
+	for i := offset; i < offset+length; i++ {
+		survived[payload[i]] = true
+	}
+	// chars after the canary
+	b := position + length
+	for p := offset + length; p < len(payload); p++ {
+		c := payload[p]
+		if b >= len(body) {
+			if isAlphanumeric(c) {
+				return "", 0, false
+			}
+			continue
+		}
+		if isAlphanumeric(c) {
+			if !strings.EqualFold(body[b:b+1], payload[p:p+1]) {
+				return "", 0, false
+			}
+			b++
+			continue
+		}
+		if size := encodedSize(body[b:], c, false); size > 0 {
+			b += size
+			continue
+		}
+		if body[b] == c {
+			survived[c] = true
+			b++
+		}
+	}
+	// chars before the canary
+	b = position - 1
+	for p := offset - 1; p >= 0; p-- {
+		c := payload[p]
+		if b < 0 {
+			if isAlphanumeric(c) {
+				return "", 0, false
+			}
+			continue
+		}
+		if isAlphanumeric(c) {
+			if !strings.EqualFold(body[b:b+1], payload[p:p+1]) {
+				return "", 0, false
+			}
+			b--
+			continue
+		}
+		if size := encodedSize(body[:b+1], c, true); size > 0 {
+			b -= size
+			continue
+		}
+		if body[b] == c {
+			if b > 0 && body[b-1] == '\\' && (p == 0 || payload[p-1] != '\\') {
+				// escaped by backslash
+				b -= 2
+				continue
+			}
+			survived[c] = true
+			b--
+		}
+	}
+
+	var chars []byte
+	for i := 0; i < len(payload); i++ {
+		c := payload[i]
+		if survived[c] && !isAlphanumeric(c) && !strings.ContainsRune(string(chars), rune(c)) {
+			chars = append(chars, c)
+		}
+	}
+	return string(chars), b + 1, true
+}
+
+// encodedSize get size of the encoded char at start or end of the component
+func encodedSize(component string, c byte, atEnd bool) int {
+	if isLetter(c) || isDigit(c) {
+		return 0
+	}
+	encodings := []string{
+		fmt.Sprintf("&#%d;", c), fmt.Sprintf("&#x%x;", c), fmt.Sprintf("&#x%02x;", c),
+		fmt.Sprintf("%%%02x", c), fmt.Sprintf("\\x%02x", c), fmt.Sprintf("\\u%04x", c),
+	}
+	if !atEnd {
+		// backslash before the char, ignored at the end as the char is checked first
+		encodings = append(encodings, "\\"+string(c))
+	}
+	switch c {
+	case '<':
+		encodings = append(encodings, "&lt;")
+	case '>':
+		encodings = append(encodings, "&gt;")
+	case '"':
+		encodings = append(encodings, "&quot;")
+	case '\'':
+		encodings = append(encodings, "&apos;")
+	case '&':
+		encodings = append(encodings, "&amp;")
+	}
+	for _, encoding := range encodings {
+		if len(component) < len(encoding) {
+			continue
+		}
+		part := component[:len(encoding)]
+		if atEnd {
+			part = component[len(component)-len(encoding):]
+		}
+		if strings.EqualFold(part, encoding) {
+			return len(encoding)
+		}
+	}
+	return 0
+}
+
+// ReflectionContexts distinct contexts of reflections in order
+func ReflectionContexts(reflections []Reflection) []string {
+	var contexts []string
+	for _, reflection := range reflections {
+		if !funk.ContainsString(contexts, reflection.Context) {
+			contexts = append(contexts, reflection.Context)
+		}
+	}
+	return contexts
+}
+
+// RecordReflections reflections of the payload of the record in response body
+func RecordReflections(record Record) []Reflection {
+	payload := record.Request.Payload
+	if payload == "" {
+		payload = record.Request.Target["payload"]
+	}
+	if payload == "" {
+		return nil
+	}
+	return Reflections(record.Response.Body, payload, isJSONResponse(record.Response))
+}
+
+// response has JSON content type
+func isJSONResponse(res libs.Response) bool {
+	for _, header := range res.Headers {
+		for key, value := range header {
+			if strings.EqualFold(key, "Content-Type") && strings.Contains(strings.ToLower(value), "json") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isAlphanumeric(c byte) bool {
+	return isLetter(c) || isDigit(c)
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
@@ -0,0 +1,96 @@
+package core
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/jaeles-project/jaeles/libs"
+)
+
+func TestReflections(t *testing.T) {
+	payload := `"'><jaeles`
+	cases := []struct {
+		body    string
+		context string
+		chars   string
+	}{
+		{`<p>Search for "'><jaeles</p>`, ContextHTML, `"'><`},
+		{`<p>Search for &quot;&#39;&gt;&lt;jaeles</p>`, ContextHTML, ``},
+		{`<input value="&quot;'>&lt;jaeles">`, ContextAttributeQuoted, `'>`},
+		{`<input value='\"\'><jaeles'>`, ContextAttributeQuoted, `><`},
+		{`<input value=%22'><jaeles>`, ContextAttributeUnquoted, `'><`},
+		{`<a href="\"'><jaeles">x</a>`, ContextURL, `'><`},
+		{`<a href="/search?q=&quot;'&gt;&lt;jaeles">x</a>`, ContextAttributeQuoted, `'`},
+		{`<img src=x onerror="load(\"'><jaeles)">`, ContextScriptCode, `'><`},
+		{`<script>var q = "\"'><jaeles";</script>`, ContextScriptString, `'><`},
+		{`<script>var q = 1; // "'><jaeles` + "\n</script>", ContextComment, `"'><`},
+		{`<script>var q = '1';` + "\n" + `search(\x22\x27\x3e\x3cjaeles)</script>`, ContextScriptCode, ``},
+		{`<!-- search "'><jaeles -->`, ContextComment, `"'><`},
+		{`<script>var a = "x";</script><b>"'><jaeles</b>`, ContextHTML, `"'><`},
+		{`{"query": "\"'><jaeles"}`, ContextJSON, `'><`},
+	}
+	for _, c := range cases {
+		reflections := Reflections(c.body, payload, false)
+		if len(reflections) != 1 {
+			t.Errorf("Expect one reflection in %v, got %v", c.body, reflections)
+			continue
+		}
+		if reflections[0].Context != c.context || reflections[0].Chars != c.chars {
+			t.Errorf("Expect %v with %q in %v, got %v", c.context, c.chars, c.body, reflections[0])
+		}
+	}
+
+	if reflections := Reflections("<p>nothing here</p>", payload, false); len(reflections) != 0 {
+		t.Errorf("Expect no reflection, got %v", reflections)
+	}
+	// canary of XSS payload is also in the page's own markup
+	xss := `<script>alert(1)</script>`
+	page := `<html><head><script src="/app.js"></script><script>var x = 1;</script></head><body>%v</body></html>`
+	if reflections := Reflections(fmt.Sprintf(page, "<p>nothing here</p>"), xss, false); len(reflections) != 0 {
+		t.Errorf("Expect unreflected payload not found, got %v", reflections)
+	}
+	reflections := Reflections(fmt.Sprintf(page, "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>"), xss, false)
+	if len(reflections) != 1 || reflections[0].Context != ContextHTML || reflections[0].Chars != "()/" {
+		t.Errorf("Expect only the encoded reflection, got %v", reflections)
+	}
+	reflections = Reflections(fmt.Sprintf(page, "<p><script>alert(1)</script></p>"), xss, false)
+	if len(reflections) != 1 || reflections[0].Chars != "<>()/" {
+		t.Errorf("Expect the reflection, got %v", reflections)
+	}
+
+	reflections = Reflections(`<title>"'><jaeles</title><input value="&quot;'&gt;<jaeles">`, payload, false)
+	if contexts := ReflectionContexts(reflections); len(contexts) != 2 {
+		t.Errorf("Expect 2 contexts, got %v", contexts)
+	}
+}
+
+func TestReflectionScripts(t *testing.T) {
+	var rec Record
+	rec.Opt = libs.Options{NoOutput: true}
+	rec.Request.Payload = `"'><jaeles`
+	rec.Response.Body = `<p>"'><jaeles</p><input value="&quot;'&gt;<jaeles">`
+
+	cases := map[string]bool{
+		`ReflectionContext() == "html,attribute-quoted"`: true,
+		`Reflected("<>")`:                             true,
+		`Reflected("\"", "attribute-quoted")`:         false,
+		`Reflected("'<", "attribute-quoted")`:         true,
+		`ReflectionContext().indexOf("script") != -1`: false,
+		`Reflected("<", "script-string")`:             false,
+	}
+	for detection, expected := range cases {
+		rec.Request.Detections = []string{detection}
+		rec.Detector()
+		if rec.IsVulnerable != expected {
+			t.Errorf("%v got %v", detection, rec.IsVulnerable)
+		}
+	}
+	// payload isn't reflected, page has its own script tag
+	rec.Request.Payload = `<script>alert(1)</script>`
+	rec.Response.Body = `<html><head><script src="/app.js"></script></head><body>ok</body></html>`
+	rec.Request.Detections = []string{`Reflected("<>")`}
+	rec.Detector()
+	if rec.IsVulnerable {
+		t.Errorf("Expect clean page not reflected")
+	}
+}
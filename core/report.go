@@ -24,6 +24,7 @@ type Vulnerability struct {
 	Length     string
 	Words      string
 	Time       string
+	Screenshot string
 }
 
 type ReportData struct {
@@ -66,6 +67,10 @@ func GenActiveReport(options libs.Options) error {
 		return err
 	}
 	result := buf.String()
+	// old templates don't know about screenshots
+	if !strings.Contains(tmpl, ".Screenshot") {
+		result = EmbedScreenshots(result, vulns)
+	}
 
 	if !strings.Contains(options.Report.ReportName, "/") {
 		options.Report.ReportName = path.Join(path.Dir(options.SummaryOutput), options.Report.ReportName)
@@ -123,6 +128,9 @@ func ParseVuln(options libs.Options) []Vulnerability {
 			ReportPath: reportPath,
 			ReportFile: filepath.Base(raw),
 		}
+		if utils.FileExists(raw + ".png") {
+			vuln.Screenshot = reportPath + ".png"
+		}
 
 		// verbose info
 		if options.VerboseSummary {
@@ -146,6 +154,41 @@ func ParseVuln(options libs.Options) []Vulnerability {
 	return vulns
 }
 
+const screenshotSection = `
+<div class="screenshots">
+<h2>Screenshots</h2>
+{{range .}}{{if .Screenshot}}<div class="screenshot">
+<p><b>[{{.SignID}}]</b> <a href="{{.ReportPath}}">{{.URL}}</a></p>
+<a href="{{.Screenshot}}"><img src="{{.Screenshot}}" alt="{{.URL}}" style="max-width: 100%; border: 1px solid #ccc;"></a>
+</div>
+{{end}}{{end}}</div>
+`
+
+// EmbedScreenshots add screenshots of findings to the report
+func EmbedScreenshots(report string, vulns []Vulnerability) string {
+	var shots []Vulnerability
+	for _, vuln := range vulns {
+		if vuln.Screenshot != "" {
+			shots = append(shots, vuln)
+		}
+	}
+	if len(shots) == 0 {
+		return report
+	}
+
+	buf := &bytes.Buffer{}
+	t := template.Must(template.New("").Parse(screenshotSection))
+	if err := t.Execute(buf, shots); err != nil {
+		utils.ErrorF("Error embedding screenshots: %v", err)
+		return report
+	}
+	index := strings.LastIndex(strings.ToLower(report), "</body>")
+	if index == -1 {
+		return report + buf.String()
+	}
+	return report[:index] + buf.String() + report[index:]
+}
+
 ///
 /* Start passive part */
 ///
@@ -3,6 +3,9 @@ package core
 import (
 	"fmt"
 	"github.com/jaeles-project/jaeles/libs"
+	"github.com/jaeles-project/jaeles/utils"
+	"path"
+	"strings"
 	"testing"
 )
 
@@ -25,3 +28,62 @@ func TestParseVuln(t *testing.T) {
 		t.Errorf("Error read jaeles-summary")
 	}
 }
+
+func TestEmbedScreenshots(t *testing.T) {
+	report := "<html><body><table></table></body></html>"
+	vulns := []Vulnerability{
+		{SignID: "xss", URL: "http://example.com/?q=<x>", ReportPath: "example.com/xss-1", Screenshot: "example.com/xss-1.png"},
+		{SignID: "sqli", URL: "http://example.com/", ReportPath: "example.com/sqli-1"},
+	}
+	result := EmbedScreenshots(report, vulns)
+	if !strings.Contains(result, `<img src="example.com/xss-1.png"`) || !strings.HasSuffix(result, "</body></html>") {
+		t.Errorf("Expect screenshot embedded in body, got %v", result)
+	}
+	if strings.Contains(result, "sqli") || strings.Contains(result, "<x>") {
+		t.Errorf("Unexpected content: %v", result)
+	}
+	if EmbedScreenshots(report, vulns[1:]) != report {
+		t.Errorf("Expect report unchanged without screenshots")
+	}
+}
+
+func TestStoreScreenshotSkipped(t *testing.T) {
+	var rec Record
+	rec.Response.Body = "<html><body>finding</body></html>"
+	if p := rec.StoreScreenshot("/tmp/jaeles-screenshot-test"); p != "" {
+		t.Errorf("Expect no screenshot without option or chrome engine, got %v", p)
+	}
+	rec.Request.Engine = "chrome"
+	rec.Response.Body = `{"json": "response"}`
+	if p := rec.StoreScreenshot("/tmp/jaeles-screenshot-test"); p != "" {
+		t.Errorf("Expect no screenshot of non HTML response, got %v", p)
+	}
+	// page is loaded again for screenshot, not in replay mode
+	rec.Request.URL = "http://example.com/"
+	rec.Response.Body = "<html><body>finding</body></html>"
+	rec.Opt.ReplayDir = "/tmp/jaeles-replay-test"
+	if p := rec.StoreScreenshot("/tmp/jaeles-screenshot-test"); p != "" {
+		t.Errorf("Expect no screenshot in replay mode, got %v", p)
+	}
+	// payload isn't sent again for screenshot
+	rec.Opt.ReplayDir = ""
+	rec.Opt.Screenshot = true
+	rec.Request.Engine = ""
+	rec.Request.Method = "POST"
+	if p := rec.StoreScreenshot("/tmp/jaeles-screenshot-test"); p != "" {
+		t.Errorf("Expect no screenshot of POST request, got %v", p)
+	}
+	rec.Request.Method = "GET"
+	rec.Request.OOB = "jx0011223344556677"
+	if p := rec.StoreScreenshot("/tmp/jaeles-screenshot-test"); p != "" {
+		t.Errorf("Expect no screenshot of request with OOB token, got %v", p)
+	}
+
+	// page captured by chrome engine is stored as it is
+	rec.Request.Engine = "chrome"
+	rec.Response.Screenshot = []byte("png")
+	output := path.Join(t.TempDir(), "finding")
+	if p := rec.StoreScreenshot(output); p != output+".png" || utils.GetFileContent(p) != "png" {
+		t.Errorf("Expect captured screenshot stored, got %v", p)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/jaeles-project/jaeles/libs"
 	"github.com/jaeles-project/jaeles/sender"
 	"github.com/jaeles-project/jaeles/utils"
+	"net/http"
 	"strings"
 )
 
@@ -23,6 +24,9 @@ type Runner struct {
 
 	Target  map[string]string
 	Records []Record
+
+	// cookie jar when session enabled
+	Jar http.CookieJar
 }
 
 // Record all information about request
@@ -57,6 +61,14 @@ type Record struct {
 	DetectString  string
 	DetectResult  string
 	ScanID        string
+	// outputs of extractors
+	Extracted map[string]string
+	// sent one by one by serial runner, so extracted values can go to later requests
+	Serial bool
+	// samples of timing mode
+	TimingSamples []TimingSample
+	// responses of payload pairs
+	PairResponses []PairResponse
 }
 
 // InitRunner init task
@@ -72,12 +84,18 @@ func InitRunner(url string, sign libs.Signature, opt libs.Options) (Runner, erro
 		runner.SendingType = "serial"
 	}
 
+	// session need requests to be sent one by one
+	if runner.Sign.Session {
+		runner.SendingType = "serial"
+		runner.NewSession()
+	}
+
 	if runner.Sign.Local == true {
 		runner.SendingType = "local"
 	}
 
 	// sending origin if we have it here
-	if runner.Sign.Origin.Method != "" || runner.Sign.Origin.Res != "" {
+	if runner.Sign.Origin.Method != "" || runner.Sign.Origin.Res != "" || len(runner.Sign.Origins) > 0 {
 		runner.PrePareOrigin()
 	}
 
@@ -122,6 +140,8 @@ func (r *Runner) GetRequests() {
 			// set somethings in record
 			rec.Request = req
 			rec.Request.Target = r.Target
+			rec.Request.Jar = r.Jar
+			rec.Request.SignID = r.Sign.ID
 			rec.Sign = r.Sign
 			rec.Opt = r.Opt
 			// assign origins here
@@ -187,15 +207,20 @@ func (r *Runner) PrePareOrigin() {
 
 	// in case we have many origin
 	if len(r.Sign.Origins) > 0 {
+		// sending other origins shouldn't replace the base one
+		baseOrigin := r.Origin
 		var origins []libs.Origin
 		for index, origin := range r.Sign.Origins {
-			origin, Target = r.SendOrigin(origin.ORequest)
-			if origin.Label == "" {
-				origin.Label = fmt.Sprintf("%v", index)
+			var sent libs.Origin
+			sent, Target = r.SendOrigin(origin.ORequest)
+			sent.Label = origin.Label
+			if sent.Label == "" {
+				sent.Label = fmt.Sprintf("%v", index)
 			}
-			origins = append(origins, origin)
+			origins = append(origins, sent)
 		}
 		r.Sign.Origins = origins
+		r.Origin = baseOrigin
 	}
 
 	r.Target = Target
@@ -219,11 +244,17 @@ func (r *Runner) SendOrigin(originReq libs.Request) (libs.Origin, map[string]str
 		originReq = ParseOrigin(originReq, originSign, r.Opt)
 	}
 
+	originReq.Jar = r.Jar
+	originReq.SignID = r.Sign.ID
 	// parse response directly without sending
 	if originReq.Res != "" {
 		originRes = ParseBurpResponse("", originReq.Res)
 	} else {
+		release := sender.AcquireHost(r.Opt, originReq.URL)
+		sender.WaitHost(r.Opt, originReq.URL)
 		originRes, err = sender.JustSend(r.Opt, originReq)
+		release()
+		sender.ThrottleHost(r.Opt, originReq.URL, originRes)
 		if err == nil {
 			if r.Opt.Verbose && (originReq.Method != "") {
 				fmt.Printf("[Sent-Origin] %v %v %v %v %v\n", originReq.Method, originReq.URL, originRes.Status, originRes.ResponseTime, len(originRes.Beautify))
@@ -240,6 +271,7 @@ func (r *Runner) SendOrigin(originReq libs.Request) (libs.Origin, map[string]str
 			r.Target[k] = v
 		}
 	}
+	UpdateSession(originReq, originRes, r.Target)
 
 	origin.ORequest = originReq
 	origin.OResponse = originRes
@@ -300,6 +332,8 @@ func (r *Runner) GenCRequests() {
 			// set somethings in record
 			rec.Request = req
 			rec.Request.Target = r.Target
+			rec.Request.Jar = r.Jar
+			rec.Request.SignID = r.Sign.ID
 			rec.Sign = r.Sign
 			rec.Opt = r.Opt
 			// assign origins here
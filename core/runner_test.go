@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"github.com/davecgh/go-spew/spew"
 	"github.com/jaeles-project/jaeles/libs"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -127,3 +129,54 @@ requests:
 
 	runner.Sending()
 }
+
+func TestRunnerSession(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: "s3cr3t", Path: "/"})
+			return
+		}
+		cookie, _ := r.Cookie("sid")
+		if cookie != nil {
+			fmt.Fprintf(w, "cookie=%v param=%v", cookie.Value, r.URL.Query().Get("sid"))
+		}
+	}))
+	defer ts.Close()
+
+	opt := libs.Options{
+		Concurrency: 3,
+		Threads:     5,
+		NoDB:        true,
+		NoOutput:    true,
+	}
+	signContent := `
+id: session-01
+session: true
+info:
+  name: Session test
+
+requests:
+  - method: GET
+    url: >-
+      {{.BaseURL}}/login
+  - method: GET
+    url: >-
+      {{.BaseURL}}/check?sid={{.cookie.sid}}
+    detections:
+      - >-
+        StringSearch("response", "cookie=s3cr3t param=s3cr3t") && Cookie("sid") == "s3cr3t"
+`
+	sign, err := ParseSignFromContent(signContent)
+	if err != nil {
+		t.Fatalf("Error parsing signature")
+	}
+	runner, err := InitRunner(ts.URL, sign, opt)
+	if err != nil {
+		t.Fatalf("Error init runner")
+	}
+	runner.InRoutine = true
+	runner.Sending()
+	if len(runner.Records) != 2 || !runner.Records[1].IsVulnerable {
+		t.Errorf("Expect cookie to be carried to the next request")
+	}
+}
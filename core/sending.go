@@ -77,9 +77,14 @@ func (r *Runner) LocalSending() {
 
 func (r *Runner) SendingSerial() {
 	var recordsSent []Record
+	extracted := make(map[string]string)
 	// Submit tasks one by one.
 	for _, record := range r.Records {
+		// values extracted by earlier requests go along so the finding of later one show them
+		record.Extracted = carryExtracted(extracted, nil)
+		record.Serial = true
 		record.DoSending()
+		carryExtracted(record.Extracted, extracted)
 		if r.InRoutine {
 			recordsSent = append(recordsSent, record)
 		}
@@ -121,6 +126,17 @@ func (r *Runner) SendingParallels() {
 	}
 }
 
+// carryExtracted copy extracted values to dst, new map is created when dst is nil
+func carryExtracted(src map[string]string, dst map[string]string) map[string]string {
+	if dst == nil {
+		dst = make(map[string]string)
+	}
+	for name, value := range src {
+		dst[name] = value
+	}
+	return dst
+}
+
 // DoSending really sending the request
 func (r *Record) DoSending() {
 	// replace things second time here with new values section
@@ -146,11 +162,12 @@ func (r *Record) DoSending() {
 	// if middleware return the response skip sending it
 	var res libs.Response
 	if r.Response.StatusCode == 0 && r.Request.Method != "" && r.Request.MiddlewareOutput == "" && req.Res == "" {
-		// sending with real browser
-		if req.Engine == "chrome" {
-			res, _ = sender.SendWithChrome(r.Opt, req)
+		if req.Timing > 0 {
+			res = r.SendTiming(&req)
+		} else if len(req.Pairs) > 0 {
+			res = r.SendPairs(&req)
 		} else {
-			res, _ = sender.JustSend(r.Opt, req)
+			res = SendRequest(r.Opt, &req)
 		}
 	}
 	// parse response directly without sending
@@ -166,6 +183,21 @@ func (r *Record) DoSending() {
 	r.Analyze()
 }
 
+// SendRequest sending the request with selected engine and respect per host limits
+func SendRequest(opt libs.Options, req *libs.Request) libs.Response {
+	var res libs.Response
+	release := sender.AcquireHost(opt, req.URL)
+	defer release()
+	sender.WaitHost(opt, req.URL)
+	if req.Engine == "raw" {
+		req.Beautify = req.Raw
+	}
+	res, _ = sender.SendWithEngine(opt, *req)
+	sender.ThrottleHost(opt, req.URL, res)
+	UpdateSession(*req, res, req.Target)
+	return res
+}
+
 // SendCRequests sending condition requests
 func (r *Runner) SendCRequests() {
 	var matchCount int
@@ -191,12 +223,7 @@ func (r *Runner) SendCRequests() {
 		// if middleware return the response skip sending it
 		var res libs.Response
 		if rec.Response.StatusCode == 0 && rec.Request.Method != "" && rec.Request.MiddlewareOutput == "" && req.Res == "" {
-			// sending with real browser
-			if req.Engine == "chrome" {
-				res, _ = sender.SendWithChrome(rec.Opt, req)
-			} else {
-				res, _ = sender.JustSend(rec.Opt, req)
-			}
+			res = SendRequest(rec.Opt, &req)
 		}
 		// parse response directly without sending
 		if req.Res != "" {
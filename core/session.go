@@ -0,0 +1,78 @@
+package core
+
+import (
+	"fmt"
+	"net/http/cookiejar"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/jaeles-project/jaeles/libs"
+	"github.com/jaeles-project/jaeles/utils"
+)
+
+// match {{.cookie.NAME}} or [[.cookie.NAME]]
+var (
+	cookieVarRegex    = regexp.MustCompile(`\{\{\s*\.cookie\.([^\s{}]+)\s*\}\}`)
+	altCookieVarRegex = regexp.MustCompile(`\[\[\s*\.cookie\.([^\s\[\]]+)\s*\]\]`)
+)
+
+// NewSession create cookie jar shared by all requests of the runner
+func (r *Runner) NewSession() {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		utils.ErrorF("Error creating session: %v", err)
+		return
+	}
+	r.Jar = jar
+}
+
+// UpdateSession store cookies of the session to target as cookie.NAME
+func UpdateSession(req libs.Request, res libs.Response, target map[string]string) {
+	if req.Jar == nil || target == nil {
+		return
+	}
+	if u, err := url.Parse(req.URL); err == nil {
+		for _, cookie := range req.Jar.Cookies(u) {
+			target[fmt.Sprintf("cookie.%v", cookie.Name)] = cookie.Value
+		}
+	}
+
+	// cookies that don't match the request path still useful for templates
+	for _, cookie := range ResponseCookies(res) {
+		target[fmt.Sprintf("cookie.%v", cookie.Name)] = cookie.Value
+	}
+}
+
+// ResolveCookies replace {{.cookie.NAME}} with cookie value of the session
+func ResolveCookies(format string, target map[string]string) string {
+	if !strings.Contains(format, ".cookie.") {
+		return format
+	}
+	return cookieVarRegex.ReplaceAllStringFunc(format, func(m string) string {
+		name := cookieVarRegex.FindStringSubmatch(m)[1]
+		if value, ok := target["cookie."+name]; ok {
+			return value
+		}
+		return m
+	})
+}
+
+// escapeCookieVariables turn cookie variable into index call so template engine can read it,
+// or keep it as it is when the cookie doesn't exist yet
+func escapeCookieVariables(format string, data map[string]string, alt bool) string {
+	if !strings.Contains(format, ".cookie.") {
+		return format
+	}
+	r, left, right := cookieVarRegex, "{{", "}}"
+	if alt {
+		r, left, right = altCookieVarRegex, "[[", "]]"
+	}
+	return r.ReplaceAllStringFunc(format, func(m string) string {
+		name := r.FindStringSubmatch(m)[1]
+		if _, ok := data["cookie."+name]; ok {
+			return fmt.Sprintf(`%vindex . "cookie.%v"%v`, left, name, right)
+		}
+		return fmt.Sprintf(`%v%q%v`, left, m, right)
+	})
+}
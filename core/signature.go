@@ -95,13 +95,42 @@ func AltResolveRequest(req *libs.Request) {
 			}
 		}
 	}
+	// cookies of the session only available after previous requests sent
+	if req.Jar != nil {
+		req.URL = ResolveCookies(req.URL, target)
+		req.Body = ResolveCookies(req.Body, target)
+		var messages []string
+		for _, message := range req.Messages {
+			messages = append(messages, ResolveCookies(message, target))
+		}
+		req.Messages = messages
+		req.Raw = ResolveCookies(req.Raw, target)
+		var headers []map[string]string
+		for _, header := range req.Headers {
+			realHeader := make(map[string]string)
+			for k, v := range header {
+				realHeader[k] = ResolveCookies(v, target)
+			}
+			headers = append(headers, realHeader)
+		}
+		req.Headers = headers
+		var detections []string
+		for _, detection := range req.Detections {
+			detections = append(detections, ResolveCookies(detection, target))
+		}
+		req.Detections = detections
+	}
 	// resolve all part again but with secondary template
 	req.URL = AltResolveVariable(req.URL, target)
 	req.Body = AltResolveVariable(req.Body, target)
+	req.Messages = AltResolveDetection(req.Messages, target)
 	req.Headers = AltResolveHeader(req.Headers, target)
 	req.Detections = AltResolveDetection(req.Detections, target)
 	req.Generators = AltResolveDetection(req.Generators, target)
 	req.Middlewares = AltResolveDetection(req.Middlewares, target)
+	if req.Engine == "raw" {
+		req.Raw = AltResolveVariable(req.Raw, target)
+	}
 }
 
 // ResolveDetection resolve detection part in YAML signature file
@@ -0,0 +1,111 @@
+package core
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/robertkrimen/otto"
+)
+
+var tokenRegex = regexp.MustCompile(`[\p{L}\p{N}_]+`)
+
+// Similarity token-based similarity of two strings from 0 to 1
+func Similarity(a string, b string) float64 {
+	if a == b {
+		return 1
+	}
+	tokensA := tokenRegex.FindAllString(a, -1)
+	tokensB := tokenRegex.FindAllString(b, -1)
+	if len(tokensA)+len(tokensB) == 0 {
+		return 1
+	}
+	counts := make(map[string]int)
+	for _, token := range tokensA {
+		counts[token]++
+	}
+	var common int
+	for _, token := range tokensB {
+		if counts[token] > 0 {
+			counts[token]--
+			common++
+		}
+	}
+	return 2 * float64(common) / float64(len(tokensA)+len(tokensB))
+}
+
+// LengthDelta absolute difference in length of two strings
+func LengthDelta(a string, b string) int {
+	delta := len(a) - len(b)
+	if delta < 0 {
+		return -delta
+	}
+	return delta
+}
+
+// LineDiff number of lines only present in one of two strings
+func LineDiff(a string, b string) int {
+	counts := make(map[string]int)
+	for _, line := range strings.Split(a, "\n") {
+		counts[strings.TrimSpace(line)]++
+	}
+	for _, line := range strings.Split(b, "\n") {
+		counts[strings.TrimSpace(line)]--
+	}
+	var diff int
+	for _, count := range counts {
+		if count < 0 {
+			count = -count
+		}
+		diff += count
+	}
+	return diff
+}
+
+// CompareComponents get components to compare with the record:
+//   - "" is the origin
+//   - "baseline" are filtering responses
+//   - index or label of origins
+func CompareComponents(record Record, label string, componentName string) []string {
+	if componentName == "" {
+		componentName = "body"
+	}
+	var components []string
+	switch label {
+	case "":
+		originRec := Record{Request: record.OriginReq, Response: record.OriginRes}
+		if record.OriginRes.StatusCode != 0 || record.OriginRes.Body != "" {
+			components = append(components, GetComponent(originRec, componentName))
+		}
+	case "baseline", "baselines":
+		for _, baseline := range record.Sign.Baselines {
+			components = append(components, GetComponent(Record{Response: baseline}, componentName))
+		}
+	default:
+		for index, origin := range record.Origins {
+			if origin.Label != label && strconv.Itoa(index) != label {
+				continue
+			}
+			originRec := Record{Request: origin.ORequest, Response: origin.OResponse}
+			components = append(components, GetComponent(originRec, componentName))
+		}
+	}
+	return components
+}
+
+// compareArguments get component of the record and ones to compare with from (label, component) arguments,
+// nothing to compare with when the origin wasn't sent or failed
+func compareArguments(record Record, arguments []otto.Value) (string, []string) {
+	var label, componentName string
+	if len(arguments) > 0 {
+		label = arguments[0].String()
+	}
+	if len(arguments) > 1 {
+		componentName = arguments[1].String()
+	}
+	components := CompareComponents(record, label, componentName)
+	if componentName == "" {
+		componentName = "body"
+	}
+	return GetComponent(record, componentName), components
+}
@@ -0,0 +1,116 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jaeles-project/jaeles/libs"
+)
+
+func TestSimilarity(t *testing.T) {
+	if Similarity("a b c d", "a b c d") != 1 || Similarity("a b", "c d") != 0 {
+		t.Errorf("Wrong similarity of equal or different strings")
+	}
+	if value := Similarity("welcome user 1 to the site", "welcome user 2 to the site"); value < 0.8 || value >= 1 {
+		t.Errorf("Expect near similarity, got %v", value)
+	}
+	if LengthDelta("abc", "abcdef") != 3 {
+		t.Errorf("Wrong length delta")
+	}
+	if diff := LineDiff("a\nb\nc", "a\nx\nc"); diff != 2 {
+		t.Errorf("Expect 2 lines diff, got %v", diff)
+	}
+}
+
+func TestSimilarityScripts(t *testing.T) {
+	var rec Record
+	rec.Opt = libs.Options{NoOutput: true}
+	rec.Response.Body = "Welcome admin, you have 3 new messages"
+	rec.OriginRes = libs.Response{StatusCode: 200, Body: "Welcome admin, you have 4 new messages"}
+	rec.Origins = []libs.Origin{
+		{Label: "false-case", OResponse: libs.Response{Body: "Login failed, please check your password"}},
+	}
+	rec.Sign.Baselines = []libs.Response{
+		{Status: "404 Not Found", Headers: []map[string]string{{"Server": "nginx"}}, Body: "Page not found"},
+		{Status: "404 Not Found", Headers: []map[string]string{{"Server": "nginx"}}, Body: "Sorry, nothing here"},
+	}
+	rec.Response.Status = "200 OK"
+	rec.Response.Headers = []map[string]string{{"Server": "nginx"}}
+
+	cases := map[string]bool{
+		`Similarity() > 0.8`:                                         true,
+		`Similarity("false-case") < 0.3`:                             true,
+		`Similarity("0") == Similarity("false-case")`:                true,
+		`Similarity("baseline") < 0.5`:                               true,
+		`Similarity("baseline", "headers") > Similarity("baseline")`: true,
+		`LengthDelta() == 0`:                                         true,
+		`LineDiff("false-case", "body") == 2`:                        true,
+		// nothing to compare with never match
+		`Similarity("not-exist") < 0.5`:  false,
+		`Similarity("not-exist") >= 0.5`: false,
+		`LengthDelta("not-exist") > 10`:  false,
+		`LineDiff("not-exist") > 0`:      false,
+	}
+	for detection, expected := range cases {
+		rec.Request.Detections = []string{detection}
+		rec.Detector()
+		if rec.IsVulnerable != expected {
+			t.Errorf("%v got %v", detection, rec.IsVulnerable)
+		}
+	}
+}
+
+func TestSimilarityOrigins(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("id") == "0" {
+			fmt.Fprint(w, "Login failed, please check your password")
+			return
+		}
+		fmt.Fprint(w, "Welcome admin, you have 3 new messages")
+	}))
+	defer ts.Close()
+
+	// signature with only labelled origins
+	signContent := `
+id: similarity-01
+info:
+  name: Similarity test
+
+origins:
+  - label: false-case
+    origin_req:
+      method: GET
+      url: >-
+        {{.BaseURL}}/?id=0
+  - origin_req:
+      method: GET
+      url: >-
+        {{.BaseURL}}/?id=1
+
+requests:
+  - method: GET
+    url: >-
+      {{.BaseURL}}/?id=1
+    detections:
+      - >-
+        Similarity("false-case") < 0.3 && Similarity("1") == 1 && Similarity() === undefined
+`
+	sign, err := ParseSignFromContent(signContent)
+	if err != nil {
+		t.Fatalf("Error parsing signature")
+	}
+	runner, err := InitRunner(ts.URL, sign, libs.Options{Concurrency: 1, Threads: 1, NoDB: true, NoOutput: true})
+	if err != nil {
+		t.Fatalf("Error init runner")
+	}
+	if len(runner.Sign.Origins) != 2 || runner.Sign.Origins[0].Label != "false-case" || runner.Sign.Origins[1].Label != "1" {
+		t.Fatalf("Expect origins sent with their labels, got %v", runner.Sign.Origins)
+	}
+	runner.InRoutine = true
+	runner.Sending()
+	if len(runner.Records) != 1 || !runner.Records[0].IsVulnerable {
+		t.Errorf("Expect comparing with labelled origins")
+	}
+}
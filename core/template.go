@@ -18,6 +18,7 @@ func ResolveVariable(format string, data map[string]string) string {
 	if !exist {
 		data["original"] = ""
 	}
+	format = escapeCookieVariables(format, data, false)
 	realFormat, err := template.New("").Funcs(sprig.TxtFuncMap()).Parse(format)
 	// when template contain {{
 	if err != nil {
@@ -54,6 +55,7 @@ func AltResolveVariable(format string, data map[string]string) string {
 	if strings.TrimSpace(format) == "" {
 		return format
 	}
+	format = escapeCookieVariables(format, data, true)
 	realFormat, err := template.New("").Delims("[[", "]]").Funcs(sprig.TxtFuncMap()).Parse(format)
 	_, exist := data["original"]
 	if !exist {
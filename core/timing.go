@@ -0,0 +1,166 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/jaeles-project/jaeles/libs"
+	"github.com/jaeles-project/jaeles/utils"
+)
+
+// placeholder of the sleep value, replaced for every sample in timing mode
+const timingPlaceholder = "[[.delay]]"
+
+// number of baseline and payload samples when repeat is not set
+const defaultTimingSamples = 3
+
+// seconds added to the longest sleep for the timeout of timing requests
+const timingTimeoutMargin = 5
+
+// TimingSample response time of a request sent with the sleep value
+type TimingSample struct {
+	Delay        int
+	ResponseTime float64
+}
+
+// SendTiming send baseline samples with zero sleep and payload samples with sleep value varied
+// between timing and double of it, the last payload sample is kept as the request of the record.
+// Failed requests are left out of the samples
+func (r *Record) SendTiming(req *libs.Request) libs.Response {
+	samples := req.Repeat
+	if samples < 2 {
+		samples = defaultTimingSamples
+	}
+	// the longest sleep mustn't hit the timeout
+	opt := r.Opt
+	minTimeout := 2*req.Timing + timingTimeoutMargin
+	if opt.Timeout < minTimeout {
+		opt.Timeout = minTimeout
+	}
+	// timeout of chrome request is the time waiting for the page
+	if req.Timeout > 0 && req.Timeout < minTimeout && req.Engine != "chrome" {
+		req.Timeout = minTimeout
+	}
+
+	var res libs.Response
+	r.TimingSamples = nil
+	// interleave baseline and payload so network changes affect both of them
+	for i := 0; i < samples; i++ {
+		baseReq := TimingRequest(*req, 0)
+		baseRes := SendRequest(opt, &baseReq)
+		if baseRes.StatusCode != 0 {
+			r.TimingSamples = append(r.TimingSamples, TimingSample{Delay: 0, ResponseTime: baseRes.ResponseTime})
+		} else {
+			utils.DebugF("[Timing] %v -- baseline failed, sample dropped", baseReq.URL)
+		}
+
+		delay := req.Timing * (1 + i%2)
+		payloadReq := TimingRequest(*req, delay)
+		res = SendRequest(opt, &payloadReq)
+		if res.StatusCode != 0 {
+			r.TimingSamples = append(r.TimingSamples, TimingSample{Delay: delay, ResponseTime: res.ResponseTime})
+		} else {
+			utils.DebugF("[Timing] %v -- delay %v failed, sample dropped", payloadReq.URL, delay)
+		}
+		utils.DebugF("[Timing] %v -- baseline %v -- delay %v: %v", payloadReq.URL, baseRes.ResponseTime, delay, res.ResponseTime)
+		if i == samples-1 {
+			*req = payloadReq
+		}
+	}
+	return res
+}
+
+// TimingRequest set the sleep value of the request
+func TimingRequest(req libs.Request, delay int) libs.Request {
+	value := fmt.Sprintf("%v", delay)
+	req.URL = strings.ReplaceAll(req.URL, timingPlaceholder, value)
+	req.Body = strings.ReplaceAll(req.Body, timingPlaceholder, value)
+	req.Raw = strings.ReplaceAll(req.Raw, timingPlaceholder, value)
+	var messages []string
+	for _, message := range req.Messages {
+		messages = append(messages, strings.ReplaceAll(message, timingPlaceholder, value))
+	}
+	req.Messages = messages
+	var headers []map[string]string
+	for _, header := range req.Headers {
+		realHeader := make(map[string]string)
+		for k, v := range header {
+			realHeader[k] = strings.ReplaceAll(v, timingPlaceholder, value)
+		}
+		headers = append(headers, realHeader)
+	}
+	req.Headers = headers
+	return req
+}
+
+// TimingSignificant check if payload samples are slower than baseline by the delay:
+//   - every payload sample is delayed at least 80% of the delay, so a single slow response can't fake it
+//   - difference of means is significant compared to the noise (Welch's t >= 3),
+//     noise of payload samples is their residual against the sleep value as the sleep is varied on purpose
+//   - response time follow the sleep value when it's varied
+//
+// no result when there are less than 2 samples of baseline or payload
+func TimingSignificant(samples []TimingSample, delay float64) (string, bool) {
+	var baseline, payload, residuals []float64
+	bySleep := make(map[int][]float64)
+	for _, sample := range samples {
+		if sample.Delay == 0 {
+			baseline = append(baseline, sample.ResponseTime)
+			continue
+		}
+		payload = append(payload, sample.ResponseTime)
+		residuals = append(residuals, sample.ResponseTime-float64(sample.Delay))
+		bySleep[sample.Delay] = append(bySleep[sample.Delay], sample.ResponseTime)
+	}
+	if len(baseline) < 2 || len(payload) < 2 {
+		return "", false
+	}
+	baseMean, baseVariance := meanVariance(baseline)
+	payloadMean, _ := meanVariance(payload)
+	_, payloadVariance := meanVariance(residuals)
+	summary := fmt.Sprintf("baseline %.3fs (+/- %.3f), payload %.3fs (+/- %.3f)", baseMean, math.Sqrt(baseVariance), payloadMean, math.Sqrt(payloadVariance))
+
+	for _, responseTime := range payload {
+		if responseTime-baseMean < 0.8*delay {
+			return summary, false
+		}
+	}
+
+	stdErr := math.Sqrt(baseVariance/float64(len(baseline)) + payloadVariance/float64(len(payload)))
+	if stdErr > 0 && (payloadMean-baseMean)/stdErr < 3 {
+		return summary, false
+	}
+
+	var sleeps []int
+	for sleep := range bySleep {
+		sleeps = append(sleeps, sleep)
+	}
+	sort.Ints(sleeps)
+	for i := 1; i < len(sleeps); i++ {
+		lower, _ := meanVariance(bySleep[sleeps[i-1]])
+		higher, _ := meanVariance(bySleep[sleeps[i]])
+		if higher-lower < 0.5*float64(sleeps[i]-sleeps[i-1]) {
+			return summary, false
+		}
+	}
+	return summary, true
+}
+
+// sample mean and variance
+func meanVariance(values []float64) (float64, float64) {
+	var sum float64
+	for _, value := range values {
+		sum += value
+	}
+	mean := sum / float64(len(values))
+	if len(values) < 2 {
+		return mean, 0
+	}
+	var squares float64
+	for _, value := range values {
+		squares += (value - mean) * (value - mean)
+	}
+	return mean, squares / float64(len(values)-1)
+}
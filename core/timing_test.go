@@ -0,0 +1,139 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jaeles-project/jaeles/libs"
+)
+
+func TestTimingSignificant(t *testing.T) {
+	// slow host without injection
+	slow := []TimingSample{{0, 4.1}, {3, 4.5}, {0, 5.9}, {6, 6.2}, {0, 4.8}, {3, 5.0}}
+	if _, ok := TimingSignificant(slow, 3); ok {
+		t.Errorf("Expect slow host not significant")
+	}
+	// delayed as the sleep value
+	injected := []TimingSample{{0, 0.2}, {3, 3.3}, {0, 0.3}, {6, 6.2}, {0, 0.25}, {3, 3.25}}
+	if _, ok := TimingSignificant(injected, 3); !ok {
+		t.Errorf("Expect injection significant")
+	}
+	// constant delay that doesn't follow the sleep value
+	constant := []TimingSample{{0, 0.2}, {3, 3.3}, {0, 0.3}, {6, 3.2}, {0, 0.25}, {3, 3.25}}
+	if _, ok := TimingSignificant(constant, 3); ok {
+		t.Errorf("Expect constant delay not significant")
+	}
+	// fast host with 2 samples, sleep values varied between d and 2d
+	fast := []TimingSample{{0, 0.0003}, {1, 1.0003}, {0, 0.0001}, {2, 2.0006}}
+	if _, ok := TimingSignificant(fast, 1); !ok {
+		t.Errorf("Expect varied sleep values not counted as noise")
+	}
+	if _, ok := TimingSignificant(injected[:2], 3); ok {
+		t.Errorf("Expect not enough samples not significant")
+	}
+}
+
+func TestTimingMode(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delay, _ := strconv.Atoi(r.URL.Query().Get("sleep"))
+		time.Sleep(time.Duration(delay) * time.Second)
+		fmt.Fprint(w, "ok")
+	}))
+	defer ts.Close()
+
+	// sleep of payload go up to 2s, timeout is raised for timing requests
+	opt := libs.Options{Concurrency: 1, Threads: 1, NoDB: true, NoOutput: true, Timeout: 1}
+	signContent := `
+id: timing-01
+info:
+  name: Timing test
+
+requests:
+  - method: GET
+    url: >-
+      {{.BaseURL}}/?sleep={{.delay}}
+    timing: 1
+    repeat: 2
+    detections:
+      - >-
+        TimingSignificant()
+`
+	sign, err := ParseSignFromContent(signContent)
+	if err != nil {
+		t.Fatalf("Error parsing signature")
+	}
+	runner, err := InitRunner(ts.URL, sign, opt)
+	if err != nil {
+		t.Fatalf("Error init runner")
+	}
+	runner.InRoutine = true
+	runner.Sending()
+	if len(runner.Records) != 1 {
+		t.Fatalf("Expect samples sent by one record, got %v", len(runner.Records))
+	}
+	rec := runner.Records[0]
+	if len(rec.TimingSamples) != 4 || rec.Request.URL != ts.URL+"/?sleep=2" {
+		t.Errorf("Wrong samples %v of %v", rec.TimingSamples, rec.Request.URL)
+	}
+	if !rec.IsVulnerable {
+		t.Errorf("Expect time-based detection, got %v", rec.TimingSamples)
+	}
+}
+
+func TestTimingFailedSamples(t *testing.T) {
+	// payload with double sleep always fail
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delay, _ := strconv.Atoi(r.URL.Query().Get("sleep"))
+		if delay > 1 {
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		time.Sleep(time.Duration(delay) * time.Second)
+		fmt.Fprint(w, "ok")
+	}))
+	defer ts.Close()
+
+	opt := libs.Options{Concurrency: 1, Threads: 1, NoDB: true, NoOutput: true, Timeout: 10}
+	signContent := `
+id: timing-02
+info:
+  name: Timing failed samples test
+
+requests:
+  - method: GET
+    url: >-
+      {{.BaseURL}}/?sleep={{.delay}}
+    timing: 1
+    repeat: 2
+    detections:
+      - >-
+        TimingSignificant()
+`
+	sign, err := ParseSignFromContent(signContent)
+	if err != nil {
+		t.Fatalf("Error parsing signature")
+	}
+	runner, err := InitRunner(ts.URL, sign, opt)
+	if err != nil {
+		t.Fatalf("Error init runner")
+	}
+	runner.InRoutine = true
+	runner.Sending()
+	rec := runner.Records[0]
+	for _, sample := range rec.TimingSamples {
+		if sample.Delay == 2 {
+			t.Errorf("Expect failed sample to be dropped, got %v", rec.TimingSamples)
+		}
+	}
+	if len(rec.TimingSamples) != 3 {
+		t.Errorf("Expect 3 samples left, got %v", rec.TimingSamples)
+	}
+	if rec.IsVulnerable {
+		t.Errorf("Expect no result with a single payload sample")
+	}
+}
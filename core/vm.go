@@ -0,0 +1,338 @@
+package core
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jaeles-project/jaeles/libs"
+	"github.com/jaeles-project/jaeles/utils"
+	"github.com/robertkrimen/otto"
+)
+
+// Scripts are compiled once and run on pooled VMs that have all functions registered,
+// functions work on the record bound to the VM right before running scripts.
+// String literals are passed to the compiled script as arguments, so scripts that only differ
+// in variables resolved inside strings share one compiled template
+
+// DefaultScriptTimeout used when no timeout was set in options
+const DefaultScriptTimeout = 30 * time.Second
+
+// maximum number of compiled scripts to keep in the cache, least recently used one is dropped first
+const maxCompiledScripts = 10000
+
+// keywords after which slash start a regular expression instead of a division
+var regexKeywords = map[string]bool{
+	"return": true, "typeof": true, "case": true, "in": true, "instanceof": true,
+	"new": true, "delete": true, "void": true, "throw": true, "do": true, "else": true,
+}
+
+// ErrScriptTimeout returned when a script run longer than the timeout
+var ErrScriptTimeout = errors.New("script timeout")
+
+var (
+	compiler   = otto.New()
+	compilerMu sync.Mutex
+	compiled   = newScriptCache(maxCompiledScripts)
+)
+
+// CompileScript parse template of the script once and reuse it for later calls,
+// return arguments the template need to run as the script
+func CompileScript(source string) (*otto.Script, []string, error) {
+	template, args := scriptTemplate(source)
+	if script, ok := compiled.Get(template); ok {
+		if script != nil {
+			return script, args, nil
+		}
+	} else {
+		script, err := compile(template)
+		compiled.Put(template, script)
+		if err == nil {
+			return script, args, nil
+		}
+	}
+
+	// template can't be compiled when the script wasn't split right, use the script as is
+	if script, ok := compiled.Get(source); ok && script != nil {
+		return script, nil, nil
+	}
+	script, err := compile(source)
+	if err != nil {
+		return nil, nil, err
+	}
+	compiled.Put(source, script)
+	return script, nil, nil
+}
+
+func compile(source string) (*otto.Script, error) {
+	compilerMu.Lock()
+	defer compilerMu.Unlock()
+	return compiler.Compile("", source)
+}
+
+// scriptTemplate replace string literals of the script with arguments,
+// literals with escape sequence and object keys are kept in the template
+func scriptTemplate(source string) (string, []string) {
+	var template strings.Builder
+	var args []string
+	// last char of the script outside of strings and comments
+	var prev byte
+	// identifier ending at prev, property name start with a dot so it's never a keyword
+	var word string
+	for i := 0; i < len(source); {
+		c := source[i]
+		switch {
+		case strings.HasPrefix(source[i:], "//"):
+			end := strings.IndexByte(source[i:], '\n')
+			if end < 0 {
+				end = len(source) - i
+			}
+			template.WriteString(source[i : i+end])
+			i += end
+		case strings.HasPrefix(source[i:], "/*"):
+			end := strings.Index(source[i+2:], "*/")
+			if end < 0 {
+				end = len(source) - i - 4
+			}
+			template.WriteString(source[i : i+end+4])
+			i += end + 4
+		case c == '/' && (prev == 0 || strings.IndexByte("(,=:[!&|?{};", prev) >= 0 || regexKeywords[word]):
+			// regular expression literal
+			end, inClass := i+1, false
+			for ; end < len(source) && source[end] != '\n'; end++ {
+				if source[end] == '\\' {
+					end++
+				} else if source[end] == '[' {
+					inClass = true
+				} else if source[end] == ']' {
+					inClass = false
+				} else if source[end] == '/' && !inClass {
+					break
+				}
+			}
+			if end >= len(source) {
+				return source, nil
+			}
+			template.WriteString(source[i : end+1])
+			prev, word, i = '/', "", end+1
+		case c == '"' || c == '\'':
+			end := i + 1
+			for ; end < len(source) && source[end] != c && source[end] != '\n'; end++ {
+				if source[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(source) || source[end] != c {
+				return source, nil
+			}
+			literal := source[i : end+1]
+			rest := strings.TrimLeft(source[end+1:], " \t\r\n")
+			isKey := (prev == '{' || prev == ',') && strings.HasPrefix(rest, ":")
+			if isKey || strings.Contains(literal, "\\") {
+				template.WriteString(literal)
+			} else {
+				args = append(args, literal[1:len(literal)-1])
+				fmt.Fprintf(&template, "__args[%d]", len(args)-1)
+			}
+			prev, word, i = c, "", end+1
+		default:
+			template.WriteByte(c)
+			if isWordChar(c) {
+				if i == 0 || !isWordChar(source[i-1]) {
+					word = ""
+					if prev == '.' {
+						word = "."
+					}
+				}
+				word += string(c)
+			} else if !isSpace(c) {
+				word = ""
+			}
+			if !isSpace(c) {
+				prev = c
+			}
+			i++
+		}
+	}
+	return template.String(), args
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// scriptCache compiled scripts by template, nil script mean the template can't be compiled
+type scriptCache struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List
+	items map[string]*list.Element
+}
+
+type cachedScript struct {
+	key    string
+	script *otto.Script
+}
+
+func newScriptCache(size int) *scriptCache {
+	return &scriptCache{size: size, order: list.New(), items: make(map[string]*list.Element)}
+}
+
+// Get get compiled script of the key
+func (c *scriptCache) Get(key string) (*otto.Script, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	item, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(item)
+	return item.Value.(*cachedScript).script, true
+}
+
+// Put store compiled script of the key, drop the least recently used one when it's full
+func (c *scriptCache) Put(key string, script *otto.Script) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if item, ok := c.items[key]; ok {
+		item.Value.(*cachedScript).script = script
+		c.order.MoveToFront(item)
+		return
+	}
+	c.items[key] = c.order.PushFront(&cachedScript{key: key, script: script})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*cachedScript).key)
+	}
+}
+
+// scriptVM VM with functions registered once
+type scriptVM struct {
+	vm *otto.Otto
+	// set the record functions work on
+	bind func(r *Record)
+	// get output collected by functions
+	output func() string
+	// get requests created by generator functions
+	requests func() []libs.Request
+	// VM is interrupted in the middle of a script so it can't be reused
+	broken bool
+}
+
+// Run run the script, stop it if it's still running after the timeout
+func (s *scriptVM) Run(source string, timeout time.Duration) (value otto.Value, err error) {
+	script, args, err := CompileScript(source)
+	if err != nil {
+		return otto.Value{}, err
+	}
+	if args == nil {
+		args = []string{}
+	}
+	s.vm.Set("__args", args)
+	if timeout <= 0 {
+		timeout = DefaultScriptTimeout
+	}
+
+	interrupt := make(chan func(), 1)
+	s.vm.Interrupt = interrupt
+	timer := time.AfterFunc(timeout, func() {
+		interrupt <- func() {
+			panic(ErrScriptTimeout)
+		}
+	})
+	defer func() {
+		timer.Stop()
+		if caught := recover(); caught != nil {
+			if caught != ErrScriptTimeout {
+				panic(caught)
+			}
+			s.broken = true
+			utils.ErrorF("Script run longer than %v: %v", timeout, source)
+			value, err = otto.Value{}, ErrScriptTimeout
+		}
+	}()
+	return s.vm.Run(script)
+}
+
+// vmPool pool of VMs of the same kind
+type vmPool struct {
+	pool sync.Pool
+}
+
+func newVMPool(build func() *scriptVM) *vmPool {
+	p := &vmPool{}
+	p.pool.New = func() interface{} {
+		s := build()
+		// keep globals right after functions registered, so the VM can be reset after each use
+		s.vm.Run(snapshotGlobals)
+		return s
+	}
+	return p
+}
+
+const snapshotGlobals = `var __globals = {};
+(function (global) {
+	var names = Object.getOwnPropertyNames(global);
+	for (var i = 0; i < names.length; i++) {
+		__globals[names[i]] = global[names[i]];
+	}
+})(this);`
+
+// remove globals that scripts created and restore the ones they changed
+const resetGlobals = `(function (global, saved) {
+	var names = Object.getOwnPropertyNames(global);
+	for (var i = 0; i < names.length; i++) {
+		var name = names[i];
+		if (name === "__globals" || name === "__args") {
+			continue;
+		}
+		if (!saved.hasOwnProperty(name)) {
+			if (!delete global[name]) {
+				global[name] = undefined;
+			}
+		} else if (global[name] !== saved[name]) {
+			global[name] = saved[name];
+		}
+	}
+})(this, __globals);`
+
+// Get get a VM and bind the record to it
+func (p *vmPool) Get(r *Record) *scriptVM {
+	s := p.pool.Get().(*scriptVM)
+	s.bind(r)
+	return s
+}
+
+// Put return the VM to the pool with globals reset, interrupted one will be dropped
+func (p *vmPool) Put(s *scriptVM) {
+	if s.broken {
+		return
+	}
+	s.bind(nil)
+	s.vm.Interrupt = nil
+	if _, err := s.vm.Run(resetGlobals); err != nil {
+		utils.DebugF("Error resetting VM: %v", err)
+		return
+	}
+	p.pool.Put(s)
+}
+
+var (
+	detectorVMs   = newVMPool(newDetectorVM)
+	concludeVMs   = newVMPool(newConcludeVM)
+	middlewareVMs = newVMPool(newMiddlewareVM)
+	generatorVMs  = newVMPool(newGeneratorVM)
+	dnsVMs        = newVMPool(newDnsVM)
+)
+
+// scriptTimeout get timeout of scripts from options
+func scriptTimeout(r *Record) time.Duration {
+	if r == nil || r.Opt.ScriptTimeout <= 0 {
+		return DefaultScriptTimeout
+	}
+	return time.Duration(r.Opt.ScriptTimeout) * time.Second
+}
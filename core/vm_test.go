@@ -0,0 +1,124 @@
+package core
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jaeles-project/jaeles/libs"
+)
+
+func TestPooledDetection(t *testing.T) {
+	opt := libs.Options{NoOutput: true, ScriptTimeout: 1}
+	detection := `StringSearch("response", "admin")`
+	for _, body := range []string{"admin panel", "nothing here", "admin"} {
+		var rec Record
+		rec.Opt = opt
+		rec.Request.Detections = []string{detection}
+		rec.Response.Body = body
+		rec.Response.Beautify = body
+		rec.Detector()
+		if rec.IsVulnerable != (body != "nothing here") {
+			t.Errorf("Detection on %q got %v", body, rec.IsVulnerable)
+		}
+	}
+
+	first, _, err := CompileScript(detection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// same script with other resolved variable
+	second, args, _ := CompileScript(`StringSearch("response", "root")`)
+	if first != second || len(args) != 2 || args[1] != "root" {
+		t.Errorf("Expect the script compiled once, got %v", args)
+	}
+	third, args, _ := CompileScript(`var o = {"a": 'x\'y'}; /"/.test(o.a) // "z"`)
+	if third == first || len(args) != 0 {
+		t.Errorf("Expect keys, escaped strings, regex and comments kept, got %v", args)
+	}
+
+	// regex after keyword isn't a division
+	for _, script := range []string{
+		`(function() { return /"/.test("ab") })()`,
+		`typeof /'/ == "object"`,
+		`(function(x) { switch (true) { case /"/.test(x): return true } })("q")`,
+		`(function() { if (false) return 1; else /'/.test("x") })()`,
+	} {
+		template, args := scriptTemplate(script)
+		if len(args) != 1 || !strings.Contains(template, "__args[0]") {
+			t.Errorf("Wrong template of %v: %v %v", script, template, args)
+		}
+		if _, _, err := CompileScript(script); err != nil {
+			t.Errorf("Error compiling %v: %v", script, err)
+		}
+	}
+	// property named like keyword and division are still split
+	if _, args := scriptTemplate(`o.return / 2 + "a" + 4 / 2 + "b"`); len(args) != 2 || args[1] != "b" {
+		t.Errorf("Expect division kept, got %v", args)
+	}
+
+	// globals of one record don't leak to the next one
+	var rec Record
+	rec.Opt = opt
+	rec.Request.Detections = []string{`var seen = true; StatusCode = function() { return 500 }; seen`}
+	rec.Detector()
+	if !rec.IsVulnerable {
+		t.Errorf("Expect first detection to be true")
+	}
+	rec = Record{Opt: opt}
+	rec.Response.StatusCode = 200
+	rec.Request.Detections = []string{`typeof seen == "undefined" && StatusCode() == 200`}
+	rec.Detector()
+	if !rec.IsVulnerable {
+		t.Errorf("Expect globals reset between records")
+	}
+}
+
+func TestGeneratorTimeout(t *testing.T) {
+	var req libs.Request
+	req.Method = "GET"
+	start := time.Now()
+	RunGenerator(req, `while(true){}`, libs.Options{ScriptTimeout: 1})
+	if time.Since(start) > 5*time.Second {
+		t.Fatalf("Expect the generator stopped after --script-timeout")
+	}
+}
+
+func TestScriptTimeout(t *testing.T) {
+	var rec Record
+	rec.Opt = libs.Options{NoOutput: true, ScriptTimeout: 1}
+	rec.Request.Detections = []string{`while(true){}`, `StringSearch("response", "ok")`}
+	rec.Response.Beautify = "ok"
+
+	start := time.Now()
+	rec.Detector()
+	if time.Since(start) > 5*time.Second {
+		t.Fatalf("Expect the script stopped after timeout")
+	}
+	if !rec.IsVulnerable {
+		t.Errorf("Expect the next detection still run after timeout")
+	}
+}
+
+func TestPipelineDetection(t *testing.T) {
+	var rec Record
+	rec.Opt = libs.Options{NoOutput: true}
+	first := libs.Response{StatusCode: 200, Body: "first"}
+	second := libs.Response{StatusCode: 404, Body: "admin"}
+	rec.Response = first
+	rec.Response.Responses = []libs.Response{first, second}
+	rec.Request.Detections = []string{`ResponseCount() == 2 && PipelineStatus(1) == 404 && PipelineSearch(1, "body", "admin") && !PipelineSearch(0, "body", "admin") && PipelineStatus(2) === undefined`}
+	rec.Detector()
+	if !rec.IsVulnerable {
+		t.Errorf("Expect detection on pipelined responses")
+	}
+
+	// response of other engines is the only one
+	rec = Record{Opt: libs.Options{NoOutput: true}}
+	rec.Response = libs.Response{StatusCode: 200, Body: "admin"}
+	rec.Request.Detections = []string{`ResponseCount() == 1 && PipelineStatus(0) == 200 && PipelineSearch(0, "body", "admin")`}
+	rec.Detector()
+	if !rec.IsVulnerable {
+		t.Errorf("Expect single response as the first pipelined one")
+	}
+}
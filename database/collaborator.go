@@ -101,3 +101,8 @@ func ImportReqLog(rec libs.Record, analyzeString string) {
 	}
 	DB.Create(&recObj)
 }
+
+// UpdateReqLog store interactions of the request
+func UpdateReqLog(interactionString string, data string) {
+	DB.Model(&models.ReqLog{}).Where("interaction_string = ?", interactionString).Update("data", data)
+}
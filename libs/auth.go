@@ -0,0 +1,21 @@
+package libs
+
+// AuthProfile describe how to login and keep the session alive during the scan
+type AuthProfile struct {
+	Login Request
+	// same as extractors of signature, run on the login response
+	Extract []Extractor
+	// raw headers inject to every request, e.g: 'Authorization: Bearer {{.token}}'
+	Headers []string
+	Relogin struct {
+		Status []int
+		Regex  string
+		// min seconds between two re-login, so endpoints always return 401 don't login every request
+		Interval int
+	}
+	// hosts get the auth headers, host of login request by default
+	Scope []string
+
+	// parsed from Headers
+	InjectHeaders map[string]string `yaml:"-"`
+}
@@ -0,0 +1,14 @@
+package libs
+
+// Extractor capture a value of the response into a named output
+type Extractor struct {
+	Name string
+	// regex, json, header or cookie
+	Type string
+	// component to search, default is body for json and response for regex
+	Part string
+	// regex, JSON path, header name or cookie name
+	Value string
+	// capture group of regex, default is the first group if any
+	Group int
+}
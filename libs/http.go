@@ -1,5 +1,7 @@
 package libs
 
+import "net/http"
+
 // Record all information about request
 type Record struct {
 	Opt           Options
@@ -20,6 +22,12 @@ type Record struct {
 	ScanID       string
 }
 
+// PairRequest requests of true and false payload at the same insertion point
+type PairRequest struct {
+	True  Request
+	False Request
+}
+
 // Origin contain map of origins
 type Origin struct {
 	Label     string
@@ -60,12 +68,55 @@ type Request struct {
 	// run when detection is true
 	PostRun []string
 
+	// capture values of the response into named outputs
+	Extractors []Extractor
+
+	// payload pairs of the same insertion point, sent together for boolean differential detection
+	Pairs []PairRequest `yaml:"-"`
+
+	// sleep seconds of time-based payload, enable timing mode that send Repeat samples
+	// of baseline and payload with [[.delay]] set to 0 and the sleep value
+	Timing int
+
+	// decode \r, \n, \t, \0, \\ and \xHH in raw request of raw engine
+	RawEscapes bool `yaml:"raw_escapes"`
+	// turn LF into CRLF in the head of each message of raw engine, raw request is sent byte for byte by default
+	RawCRLF bool `yaml:"raw_crlf"`
+
+	// don't reuse pooled connection for this request
+	FreshConn bool `yaml:"fresh"`
+
+	// override global TLS profile
+	TLS TLSConfig `yaml:"tls"`
+
+	// cookie jar of the session if enabled in signature
+	Jar http.CookieJar `yaml:"-" json:"-"`
+	// signature ID the request belong to
+	SignID string `yaml:"-"`
+
+	// frames to send with websocket engine, Body is used when empty
+	Messages []string
+
+	// interaction ID of {{.oob}} in this request
+	OOB string `yaml:"-"`
+
 	// for fuzzing
 	Generators []string
 	Encoding   string
 	Target     map[string]string
 }
 
+// TLSConfig custom TLS profile like client certificate for mTLS
+type TLSConfig struct {
+	Cert       string
+	Key        string
+	CA         string
+	MinVersion string   `yaml:"min_version"`
+	MaxVersion string   `yaml:"max_version"`
+	Ciphers    []string `yaml:"ciphers"`
+	SNI        string   `yaml:"sni"`
+}
+
 // Response all information about response
 type Response struct {
 	HasPopUp   bool
@@ -78,4 +129,21 @@ type Response struct {
 	ResponseTime float64
 	Length       int
 	Beautify     string
+
+	// collected by chrome engine
+	Browser BrowserData
+	// page captured by chrome engine once it's loaded
+	Screenshot []byte `json:"-"`
+
+	// every response read by raw engine in order, the first one is also the response itself
+	Responses []Response `json:",omitempty"`
+}
+
+// BrowserData events happened in the page while loading it with chrome engine
+type BrowserData struct {
+	Console     []string
+	Errors      []string
+	Navigations []string
+	Requests    []string
+	Sinks       []string
 }
@@ -0,0 +1,18 @@
+package libs
+
+// Network raw TCP or TLS request
+type Network struct {
+	Host string
+	Port string
+	TLS  bool
+	// bytes to send, support \r\n and \xHH escapes
+	Data string
+	// stop reading when receiving this string
+	Delimiter string
+	Timeout   int
+
+	Detections []string
+
+	// run when detection is true
+	PostRun []string
+}
@@ -34,6 +34,9 @@ type Options struct {
 	Concurrency       int
 	Threads           int
 	Delay             int
+	RateLimit         int
+	MaxPerHost        int
+	MaxPerIP          int
 	Timeout           int
 	Refresh           int
 	Retry             int
@@ -65,6 +68,35 @@ type Options struct {
 	ChunkSize    int
 	ChunkLimit   int
 
+	// rotate proxies from file
+	ProxyFile   string
+	ProxyRotate string
+	// global TLS profile
+	TLS TLSConfig
+	// authentication profile file
+	AuthFile string
+	// max tabs per browser for chrome engine
+	ChromeTabs int
+	// record all traffic to HAR file
+	HarFile string
+	// record responses to folder then replay them without network
+	RecordDir string
+	ReplayDir string
+	// take screenshot of any HTML finding, not only chrome engine
+	Screenshot bool
+	// out of band server, host is used in payload and server to get interactions
+	OOBHost   string
+	OOBServer string
+	OOBSecret string
+	OOBWait   int
+	OOBGrace  int
+
+	// Scripts
+	ScriptTimeout int
+
+	// request slot of the host reserved by the scheduler for the running job
+	Slot HostSlot `json:"-"`
+
 	Mics   Mics
 	Scan   Scan
 	Server Server
@@ -121,13 +153,23 @@ type Config struct {
 	PrivateKey string
 }
 
+// HostSlot request slot of a host reserved for a whole job
+type HostSlot interface {
+	// Take use the reserved slot for a request to the URL, ok is false when the slot isn't for its host
+	Take(rawURL string) (release func(), ok bool)
+}
+
 // Job define job for running routine
 type Job struct {
 	URL       string
 	Checksums []string
+	// filtering responses
+	Baselines []Response
 	Sign      Signature
 	// the base response
 	Response Response
+	// slot reserved by the scheduler, nil when host isn't limited
+	Slot HostSlot
 }
 
 // VulnData vulnerable Data
@@ -147,4 +189,7 @@ type VulnData struct {
 	ContentLength string
 	OutputFile    string
 	SignatureFile string
+	Screenshot    string
+	// outputs of extractors
+	Extracted map[string]string `json:",omitempty"`
 }
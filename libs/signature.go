@@ -12,6 +12,8 @@ type Signature struct {
 	OverrideFilerPaths bool
 	FilteringPaths     []string `yaml:"fpaths"`
 	Checksums          []string
+	// filtering responses for fuzzy comparison
+	Baselines []Response `yaml:"-"`
 	// local analyze
 	Local    bool
 	Response Response
@@ -24,6 +26,10 @@ type Signature struct {
 	Serial     bool
 	BasePath   bool
 	CleanSlash bool
+	// don't reuse pooled connection for all requests
+	FreshConn bool `yaml:"fresh"`
+	// carry cookies across origin, check requests and requests
+	Session bool
 	// Detect once
 	Noutput      bool
 	Donce        bool
@@ -59,9 +65,15 @@ type Signature struct {
 	Variables  []map[string]string
 	Target     map[string]string
 
+	// true and false condition payloads sent together for each insertion point
+	Pairs []Pair
+
 	// for dns part only
 	Dns []Dns
 
+	// for raw TCP/TLS part only
+	Network []Network
+
 	// similar to passive but only applied in local check
 	Rules []Rule
 
@@ -81,3 +93,9 @@ type Routine struct {
 		Invokes    []string `yaml:"invokes"`
 	} `yaml:"logics"`
 }
+
+// Pair payloads of true and false condition, e.g: ' AND '1'='1 and ' AND '1'='2
+type Pair struct {
+	True  string `yaml:"true"`
+	False string `yaml:"false"`
+}
@@ -0,0 +1,85 @@
+package oob
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Client get interactions from the server
+type Client struct {
+	URL    string
+	Secret string
+	client *http.Client
+}
+
+// NewClient create new client, server is the address of the HTTP listener
+func NewClient(server string, secret string) *Client {
+	if !strings.HasPrefix(server, "http://") && !strings.HasPrefix(server, "https://") {
+		server = "http://" + server
+	}
+	return &Client{
+		URL:    strings.TrimRight(server, "/"),
+		Secret: secret,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// All get interactions of every ID
+func (c *Client) All() ([]Interaction, error) {
+	return c.Since(0)
+}
+
+// Since get interactions of every ID come after the cursor
+func (c *Client) Since(cursor int64) ([]Interaction, error) {
+	return c.get(fmt.Sprintf("%v?since=%v", APIPath, cursor))
+}
+
+// Poll get current interactions of the ID
+func (c *Client) Poll(id string) ([]Interaction, error) {
+	return c.get(APIPath + id)
+}
+
+func (c *Client) get(path string) ([]Interaction, error) {
+	var interactions []Interaction
+	req, err := http.NewRequest("GET", c.URL+path, nil)
+	if err != nil {
+		return interactions, err
+	}
+	if c.Secret != "" {
+		req.Header.Set(secretHeader, c.Secret)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return interactions, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return interactions, fmt.Errorf("oob server return %v", resp.Status)
+	}
+	err = json.NewDecoder(resp.Body).Decode(&interactions)
+	return interactions, err
+}
+
+// Wait poll the server until having interaction of the protocol or timeout, empty protocol mean any
+func (c *Client) Wait(id string, protocol string, timeout time.Duration) ([]Interaction, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		interactions, err := c.Poll(id)
+		if err != nil {
+			return nil, err
+		}
+		var matched []Interaction
+		for _, interaction := range interactions {
+			if protocol == "" || strings.EqualFold(interaction.Protocol, protocol) {
+				matched = append(matched, interaction)
+			}
+		}
+		if len(matched) > 0 || time.Now().After(deadline) {
+			return matched, nil
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
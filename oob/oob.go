@@ -0,0 +1,69 @@
+package oob
+
+// Self-hosted out of band interaction server and client
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// APIPath path of the API to get interactions, never logged as interaction
+const APIPath = "/__jaeles_oob/"
+
+// header carry the secret to call the API
+const secretHeader = "X-OOB-Secret"
+
+// every interaction ID look like this so it can be found in any part of the request
+var idRegex = regexp.MustCompile(`jx[0-9a-f]{16}`)
+
+// Interaction a DNS query or HTTP request made to the server
+type Interaction struct {
+	// increase with every interaction, used as cursor to poll new ones
+	Seq        int64
+	ID         string
+	Protocol   string
+	RemoteAddr string
+	Data       string
+	Time       time.Time
+}
+
+// NewID generate a new interaction ID
+func NewID() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return "jx" + hex.EncodeToString(buf)
+}
+
+// Payload build the value of {{.oob}}, subdomain for domain and path for IP
+func Payload(host string, id string) string {
+	hostname := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		hostname = h
+	}
+	if net.ParseIP(hostname) != nil {
+		return host + "/" + id
+	}
+	return id + "." + host
+}
+
+// IsLoopback check if the listen address only accept local connections
+func IsLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// ExtractIDs get all interaction IDs in the input
+func ExtractIDs(raw string) []string {
+	return idRegex.FindAllString(strings.ToLower(raw), -1)
+}
@@ -0,0 +1,112 @@
+package oob
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func TestPayload(t *testing.T) {
+	if Payload("oob.example.com", "jx0011223344556677") != "jx0011223344556677.oob.example.com" {
+		t.Errorf("Expect subdomain payload for domain")
+	}
+	if Payload("127.0.0.1:8088", "jx0011223344556677") != "127.0.0.1:8088/jx0011223344556677" {
+		t.Errorf("Expect path payload for IP")
+	}
+	if ids := ExtractIDs("GET /x HTTP/1.1\nHost: JX0011223344556677.oob.example.com"); len(ids) != 1 || ids[0] != "jx0011223344556677" {
+		t.Errorf("Unexpected IDs: %v", ids)
+	}
+}
+
+func TestInteractions(t *testing.T) {
+	server := NewServer("127.0.0.1", "s3cr3t")
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dnsServer := &dns.Server{PacketConn: pc, Handler: server}
+	go dnsServer.ActivateAndServe()
+	defer dnsServer.Shutdown()
+
+	id := NewID()
+	// target call us back via HTTP and DNS
+	http.Get(ts.URL + "/" + id + "?x=1")
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(id+".oob.example.com"), dns.TypeA)
+	reply, err := dns.Exchange(msg, pc.LocalAddr().String())
+	if err != nil || len(reply.Answer) != 1 || !strings.Contains(reply.Answer[0].String(), "127.0.0.1") {
+		t.Errorf("Unexpected DNS answer: %v %v", reply, err)
+	}
+
+	client := NewClient(ts.URL, "s3cr3t")
+	interactions, err := client.Wait(id, "", time.Second)
+	if err != nil || len(interactions) != 2 {
+		t.Fatalf("Expect 2 interactions, got %v %v", interactions, err)
+	}
+	interactions, _ = client.Wait(id, "dns", time.Second)
+	if len(interactions) != 1 || interactions[0].Protocol != "dns" {
+		t.Errorf("Expect DNS interaction, got %v", interactions)
+	}
+	interactions, _ = client.Wait(NewID(), "", time.Second)
+	if len(interactions) != 0 {
+		t.Errorf("Expect no interaction for unknown ID, got %v", interactions)
+	}
+	all, _ := client.All()
+	if len(all) != 2 || all[0].Seq >= all[1].Seq {
+		t.Fatalf("Expect interactions in order, got %v", all)
+	}
+	interactions, err = client.Since(all[0].Seq)
+	if err != nil || len(interactions) != 1 || interactions[0].Seq != all[1].Seq {
+		t.Errorf("Expect only interactions after the cursor, got %v %v", interactions, err)
+	}
+	if interactions, _ = client.Since(all[1].Seq); len(interactions) != 0 {
+		t.Errorf("Expect nothing new, got %v", interactions)
+	}
+	if _, err := NewClient(ts.URL, "wrong").Poll(id); err == nil {
+		t.Errorf("Expect wrong secret to be rejected")
+	}
+	if _, err := NewClient(ts.URL, "").Poll(id); err == nil {
+		t.Errorf("Expect missing secret to be rejected")
+	}
+
+	// only the start of a huge body is kept
+	big := NewID()
+	http.Post(ts.URL+"/"+big, "text/plain", strings.NewReader(strings.Repeat("a", 2*maxRecordedBody)))
+	interactions, _ = client.Wait(big, "", time.Second)
+	if len(interactions) != 1 || len(interactions[0].Data) > maxRecordedBody+1024 {
+		t.Errorf("Expect body of interaction truncated, got %v interactions", len(interactions))
+	}
+}
+
+func TestServerLimits(t *testing.T) {
+	server := NewServer("", "")
+	server.MaxInteractions = 2
+	first, second, third := NewID(), NewID(), NewID()
+	server.Record("http", "1.1.1.1", first)
+	server.Record("http", "1.1.1.1", second)
+	server.Record("http", "1.1.1.1", third)
+	if len(server.All()) != 2 || len(server.Get(first)) != 0 || len(server.Get(third)) != 1 {
+		t.Errorf("Expect oldest interaction dropped, got %v", server.All())
+	}
+
+	server.TTL = 50 * time.Millisecond
+	time.Sleep(100 * time.Millisecond)
+	if len(server.All()) != 0 || len(server.Get(third)) != 0 {
+		t.Errorf("Expect interactions expired, got %v", server.All())
+	}
+
+	if !IsLoopback("127.0.0.1:8088") || !IsLoopback("localhost:80") || !IsLoopback("[::1]:80") {
+		t.Errorf("Expect loopback addresses")
+	}
+	if IsLoopback("0.0.0.0:80") || IsLoopback(":80") || IsLoopback("1.2.3.4:80") {
+		t.Errorf("Expect public addresses not loopback")
+	}
+}
@@ -0,0 +1,196 @@
+package oob
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jaeles-project/jaeles/utils"
+	"github.com/miekg/dns"
+)
+
+// limits of interactions kept in memory, oldest ones are dropped first
+const (
+	DefaultMaxInteractions = 10000
+	DefaultInteractionTTL  = 24 * time.Hour
+)
+
+// max body size kept of HTTP interaction, the rest is dropped
+const maxRecordedBody = 64 << 10
+
+// Server store interactions in memory and serve them via API
+type Server struct {
+	// answer A record of DNS query with this IP
+	IP     string
+	Secret string
+	// max number and lifetime of stored interactions
+	MaxInteractions int
+	TTL             time.Duration
+
+	mu           sync.Mutex
+	interactions map[string][]Interaction
+	// every interaction in the order they come
+	log []Interaction
+	seq int64
+}
+
+// NewServer create new server
+func NewServer(ip string, secret string) *Server {
+	return &Server{
+		IP:              ip,
+		Secret:          secret,
+		MaxInteractions: DefaultMaxInteractions,
+		TTL:             DefaultInteractionTTL,
+		interactions:    make(map[string][]Interaction),
+	}
+}
+
+// Record store interaction for every ID found in the data
+func (s *Server) Record(protocol string, remoteAddr string, data string) {
+	ids := ExtractIDs(data)
+	if len(ids) == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		s.seq++
+		interaction := Interaction{
+			Seq:        s.seq,
+			ID:         id,
+			Protocol:   protocol,
+			RemoteAddr: remoteAddr,
+			Data:       data,
+			Time:       time.Now(),
+		}
+		s.interactions[id] = append(s.interactions[id], interaction)
+		s.log = append(s.log, interaction)
+		utils.GoodF("[OOB][%v] %v from %v", protocol, id, remoteAddr)
+	}
+	s.prune()
+}
+
+// prune drop interactions over the limit or expired, must hold the lock
+func (s *Server) prune() {
+	expired := time.Now().Add(-s.TTL)
+	for len(s.log) > 0 {
+		oldest := s.log[0]
+		overLimit := s.MaxInteractions > 0 && len(s.log) > s.MaxInteractions
+		if !overLimit && (s.TTL <= 0 || oldest.Time.After(expired)) {
+			return
+		}
+		s.log = s.log[1:]
+		if items := s.interactions[oldest.ID][1:]; len(items) > 0 {
+			s.interactions[oldest.ID] = items
+		} else {
+			delete(s.interactions, oldest.ID)
+		}
+	}
+}
+
+// Get interactions of the ID
+func (s *Server) Get(id string) []Interaction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune()
+	return append([]Interaction{}, s.interactions[strings.ToLower(id)]...)
+}
+
+// All get interactions of every ID
+func (s *Server) All() []Interaction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune()
+	return append([]Interaction{}, s.log...)
+}
+
+// Since get interactions come after the cursor
+func (s *Server) Since(cursor int64) []Interaction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune()
+	index := sort.Search(len(s.log), func(i int) bool {
+		return s.log[i].Seq > cursor
+	})
+	return append([]Interaction{}, s.log[index:]...)
+}
+
+// ServeHTTP log every request, except the API one
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, APIPath) {
+		if s.Secret != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get(secretHeader)), []byte(s.Secret)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		id := strings.TrimPrefix(r.URL.Path, APIPath)
+		w.Header().Set("Content-Type", "application/json")
+		if id == "" {
+			cursor, _ := strconv.ParseInt(r.URL.Query().Get("since"), 10, 64)
+			json.NewEncoder(w).Encode(s.Since(cursor))
+			return
+		}
+		json.NewEncoder(w).Encode(s.Get(id))
+		return
+	}
+
+	// ID is in the host or path most of the time, don't hold a huge body for it
+	r.Body = io.NopCloser(io.LimitReader(r.Body, maxRecordedBody))
+	raw, err := httputil.DumpRequest(r, true)
+	if err != nil {
+		raw = []byte(fmt.Sprintf("%v %v %v\nHost: %v", r.Method, r.URL.String(), r.Proto, r.Host))
+	}
+	s.Record("http", r.RemoteAddr, string(raw))
+	w.Write([]byte("jaeles-oob"))
+}
+
+// ServeDNS log every query and answer A record with our IP
+func (s *Server) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	msg := new(dns.Msg)
+	msg.SetReply(r)
+	msg.Authoritative = true
+	for _, question := range r.Question {
+		s.Record("dns", w.RemoteAddr().String(), fmt.Sprintf("%v %v", dns.TypeToString[question.Qtype], question.Name))
+		if question.Qtype != dns.TypeA || s.IP == "" {
+			continue
+		}
+		msg.Answer = append(msg.Answer, &dns.A{
+			Hdr: dns.RR_Header{Name: question.Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+			A:   net.ParseIP(s.IP),
+		})
+	}
+	w.WriteMsg(msg)
+}
+
+// ListenHTTP start HTTP listener
+func (s *Server) ListenHTTP(addr string) error {
+	utils.InforF("OOB HTTP listener on %v", addr)
+	server := &http.Server{
+		Addr:         addr,
+		Handler:      s,
+		ReadTimeout:  30 * time.Second,
+		WriteTimeout: 30 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+	return server.ListenAndServe()
+}
+
+// ListenDNS start DNS listener on UDP
+func (s *Server) ListenDNS(addr string) error {
+	utils.InforF("OOB DNS listener on %v", addr)
+	server := &dns.Server{Addr: addr, Net: "udp", Handler: s}
+	return server.ListenAndServe()
+}
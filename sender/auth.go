@@ -0,0 +1,252 @@
+package sender
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jaeles-project/jaeles/libs"
+	"github.com/jaeles-project/jaeles/utils"
+)
+
+// ResponseExtractor get value of the extractor from the response, same as extractors of signature
+type ResponseExtractor func(res libs.Response, extractor libs.Extractor) (string, bool)
+
+// AuthSession keep credentials of the auth profile and refresh them when expired
+type AuthSession struct {
+	mu          sync.RWMutex
+	profile     libs.AuthProfile
+	extract     ResponseExtractor
+	headers     map[string]string
+	generation  int
+	relogin     *regexp.Regexp
+	scope       []string
+	lastRelogin time.Time
+}
+
+// default min time between two re-login
+const defaultReloginInterval = 30 * time.Second
+
+// end of the headers in raw message
+var rawHeaderEndRegex = regexp.MustCompile(`\r?\n\r?\n`)
+
+var (
+	authSession *AuthSession
+	// only one request doing re-login at a time
+	authLock sync.Mutex
+)
+
+// InitAuth login with auth profile and inject its headers to every request in scope after that,
+// values of the login response are extracted the same way extractors of signature do
+func InitAuth(options libs.Options, profile libs.AuthProfile, extract ResponseExtractor) error {
+	session := &AuthSession{profile: profile, extract: extract}
+	for _, host := range profile.Scope {
+		session.scope = append(session.scope, strings.ToLower(strings.TrimSpace(host)))
+	}
+	if len(session.scope) == 0 {
+		u, err := url.Parse(profile.Login.URL)
+		if err != nil || u.Host == "" {
+			return fmt.Errorf("invalid login URL: %v", profile.Login.URL)
+		}
+		session.scope = []string{strings.ToLower(u.Host)}
+	}
+	if profile.Relogin.Regex != "" {
+		r, err := regexp.Compile(profile.Relogin.Regex)
+		if err != nil {
+			return fmt.Errorf("invalid relogin regex: %v", err)
+		}
+		session.relogin = r
+	}
+	if err := session.Login(options); err != nil {
+		return err
+	}
+	authSession = session
+	return nil
+}
+
+// Login send login request and build headers from extracted values
+func (a *AuthSession) Login(options libs.Options) error {
+	res, err := doSend(options, a.profile.Login)
+	if err != nil {
+		return fmt.Errorf("error sending login request: %v", err)
+	}
+
+	values := make(map[string]string)
+	for _, extract := range a.profile.Extract {
+		value, ok := a.extract(res, extract)
+		if !ok || value == "" {
+			return fmt.Errorf("can't extract %v from login response", extract.Name)
+		}
+		values[extract.Name] = value
+	}
+
+	headers := make(map[string]string)
+	for k, v := range a.profile.InjectHeaders {
+		for name, value := range values {
+			v = strings.ReplaceAll(v, fmt.Sprintf("{{.%v}}", name), value)
+		}
+		headers[k] = v
+	}
+
+	a.mu.Lock()
+	a.headers = headers
+	a.generation++
+	a.mu.Unlock()
+	utils.InforF("Logged in with auth profile: %v", a.profile.Login.URL)
+	return nil
+}
+
+// Apply inject auth headers to request, return generation of credentials being used
+func (a *AuthSession) Apply(req *libs.Request) int {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	var headers []map[string]string
+	for _, header := range req.Headers {
+		element := make(map[string]string)
+		for k, v := range header {
+			if !a.hasHeader(k) {
+				element[k] = v
+			}
+		}
+		if len(element) > 0 {
+			headers = append(headers, element)
+		}
+	}
+	for k, v := range a.headers {
+		headers = append(headers, map[string]string{k: v})
+	}
+	req.Headers = headers
+	// raw engine send the raw message, not the headers
+	if req.Engine == "raw" && req.Raw != "" {
+		req.Raw = SetRawHeaders(req.Raw, a.headers)
+	}
+	return a.generation
+}
+
+// SetRawHeaders replace or add headers in the first request of raw message, keep its line ending
+func SetRawHeaders(raw string, headers map[string]string) string {
+	if len(headers) == 0 {
+		return raw
+	}
+	head, rest := raw, ""
+	if loc := rawHeaderEndRegex.FindStringIndex(raw); loc != nil {
+		head, rest = raw[:loc[0]], raw[loc[0]:]
+	} else {
+		head = strings.TrimRight(raw, "\r\n")
+		rest = raw[len(head):]
+	}
+	newline := "\n"
+	if strings.Contains(head, "\r\n") || strings.HasPrefix(rest, "\r\n") {
+		newline = "\r\n"
+	}
+
+	lines := strings.Split(strings.ReplaceAll(head, "\r\n", "\n"), "\n")
+	kept := lines[:1]
+	for _, line := range lines[1:] {
+		name := strings.TrimSpace(strings.SplitN(line, ":", 2)[0])
+		var replaced bool
+		for k := range headers {
+			if strings.EqualFold(k, name) {
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			kept = append(kept, line)
+		}
+	}
+	var keys []string
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		kept = append(kept, fmt.Sprintf("%v: %v", k, headers[k]))
+	}
+	return strings.Join(kept, newline) + rest
+}
+
+func (a *AuthSession) hasHeader(name string) bool {
+	for k := range a.headers {
+		if strings.EqualFold(k, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// InScope check if the request go to host of the auth profile, so credentials don't leak to other targets
+func (a *AuthSession) InScope(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Host)
+	for _, scope := range a.scope {
+		if host == scope || strings.ToLower(u.Hostname()) == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Expired check if response match relogin trigger
+func (a *AuthSession) Expired(res libs.Response) bool {
+	for _, status := range a.profile.Relogin.Status {
+		if res.StatusCode == status {
+			return true
+		}
+	}
+	if a.relogin != nil && a.relogin.MatchString(res.Beautify) {
+		return true
+	}
+	return false
+}
+
+// Refresh login again unless another request already did it or the last re-login is too recent,
+// return true when credentials changed since the generation
+func (a *AuthSession) Refresh(options libs.Options, generation int) (bool, error) {
+	authLock.Lock()
+	defer authLock.Unlock()
+	a.mu.RLock()
+	current := a.generation
+	a.mu.RUnlock()
+	if current != generation {
+		return true, nil
+	}
+
+	interval := defaultReloginInterval
+	if a.profile.Relogin.Interval > 0 {
+		interval = time.Duration(a.profile.Relogin.Interval) * time.Second
+	}
+	if time.Since(a.lastRelogin) < interval {
+		utils.DebugF("Skip re-login, last one was %v ago", time.Since(a.lastRelogin))
+		return false, nil
+	}
+	a.lastRelogin = time.Now()
+	utils.InforF("Session expired, login again")
+	return true, a.Login(options)
+}
+
+// sendWithAuth send request with auth headers and retry once after re-login
+func sendWithAuth(options libs.Options, req libs.Request, send func(libs.Options, libs.Request) (libs.Response, error)) (libs.Response, error) {
+	generation := authSession.Apply(&req)
+	res, err := send(options, req)
+	if err != nil || !authSession.Expired(res) {
+		return res, err
+	}
+	refreshed, rerr := authSession.Refresh(options, generation)
+	if rerr != nil {
+		utils.ErrorF("%v", rerr)
+		return res, err
+	}
+	if !refreshed {
+		return res, err
+	}
+	authSession.Apply(&req)
+	return send(options, req)
+}
@@ -0,0 +1,159 @@
+package sender
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/jaeles-project/jaeles/libs"
+)
+
+// jsonExtractor stand for extractors of core, that can't be imported here
+func jsonExtractor(res libs.Response, extractor libs.Extractor) (string, bool) {
+	parsed, err := gabs.ParseJSON([]byte(res.Body))
+	if err != nil {
+		return "", false
+	}
+	value, ok := parsed.Path(extractor.Value).Data().(string)
+	return value, ok
+}
+
+func TestAuthRelogin(t *testing.T) {
+	var logins int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			count := atomic.AddInt32(&logins, 1)
+			fmt.Fprintf(w, `{"data": {"token": "token-%v"}}`, count)
+			return
+		}
+		// legitimately protected endpoint
+		if r.URL.Path == "/admin" {
+			w.WriteHeader(401)
+			return
+		}
+		// first token expired right away
+		if r.Header.Get("Authorization") != "Bearer token-2" {
+			w.WriteHeader(401)
+			return
+		}
+		w.Write([]byte("welcome"))
+	}))
+	defer ts.Close()
+	defer func() { authSession = nil }()
+
+	var profile libs.AuthProfile
+	profile.Login = libs.Request{Method: "POST", URL: ts.URL + "/login"}
+	profile.Extract = []libs.Extractor{{Name: "token", Type: "json", Value: "data.token"}}
+	profile.InjectHeaders = map[string]string{"Authorization": "Bearer {{.token}}"}
+	profile.Relogin.Status = []int{401}
+
+	var options libs.Options
+	options.Timeout = 5
+	if err := InitAuth(options, profile, jsonExtractor); err != nil {
+		t.Fatalf("Error login: %v", err)
+	}
+
+	req := libs.Request{Method: "GET", URL: ts.URL + "/api", Headers: []map[string]string{{"Authorization": "old"}}}
+	res, err := JustSend(options, req)
+	if err != nil || res.StatusCode != 200 || res.Body != "welcome" {
+		t.Errorf("Expect request to be retried after re-login, got %v %v", res.StatusCode, err)
+	}
+	if logins != 2 {
+		t.Errorf("Expect to login twice, got %v", logins)
+	}
+
+	// re-login is debounced so 401 endpoint doesn't login every request
+	for i := 0; i < 3; i++ {
+		req = libs.Request{Method: "GET", URL: ts.URL + "/admin"}
+		if res, _ := JustSend(options, req); res.StatusCode != 401 {
+			t.Errorf("Expect 401 from admin endpoint, got %v", res.StatusCode)
+		}
+	}
+	if logins != 2 {
+		t.Errorf("Expect no more login within relogin interval, got %v", logins)
+	}
+}
+
+func TestAuthScope(t *testing.T) {
+	var received string
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get("Authorization")
+	}))
+	defer other.Close()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			fmt.Fprint(w, `{"token": "secret"}`)
+			return
+		}
+		received = r.Header.Get("Authorization")
+	}))
+	defer ts.Close()
+	defer func() { authSession = nil }()
+
+	var profile libs.AuthProfile
+	profile.Login = libs.Request{Method: "POST", URL: ts.URL + "/login"}
+	profile.Extract = []libs.Extractor{{Name: "token", Type: "json", Value: "token"}}
+	profile.InjectHeaders = map[string]string{"Authorization": "Bearer {{.token}}"}
+	options := libs.Options{Timeout: 5}
+	if err := InitAuth(options, profile, jsonExtractor); err != nil {
+		t.Fatalf("Error login: %v", err)
+	}
+
+	JustSend(options, libs.Request{Method: "GET", URL: ts.URL + "/api"})
+	if received != "Bearer secret" {
+		t.Errorf("Expect auth headers sent to login host, got %q", received)
+	}
+	JustSend(options, libs.Request{Method: "GET", URL: other.URL + "/api"})
+	if received != "" {
+		t.Errorf("Expect auth headers not sent to other host, got %q", received)
+	}
+}
+
+func TestAuthEngines(t *testing.T) {
+	var received []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			fmt.Fprint(w, `{"token": "secret"}`)
+			return
+		}
+		received = append(received, r.Header.Get("Authorization"))
+	}))
+	defer ts.Close()
+	defer func() { authSession = nil }()
+
+	var profile libs.AuthProfile
+	profile.Login = libs.Request{Method: "POST", URL: ts.URL + "/login"}
+	profile.Extract = []libs.Extractor{{Name: "token", Type: "json", Value: "token"}}
+	profile.InjectHeaders = map[string]string{"Authorization": "Bearer {{.token}}"}
+	options := libs.Options{Timeout: 5}
+	if err := InitAuth(options, profile, jsonExtractor); err != nil {
+		t.Fatalf("Error login: %v", err)
+	}
+
+	host := strings.TrimPrefix(ts.URL, "http://")
+	raw := "GET /raw HTTP/1.1\r\nHost: " + host + "\r\nauthorization: old\r\nConnection: close\r\n\r\n"
+	if _, err := SendWithEngine(options, libs.Request{Engine: "raw", Method: "GET", URL: ts.URL + "/raw", Raw: raw}); err != nil {
+		t.Fatalf("Error sending raw request: %v", err)
+	}
+	if len(received) != 1 || received[0] != "Bearer secret" {
+		t.Errorf("Expect auth headers in raw request, got %v", received)
+	}
+}
+
+func TestSetRawHeaders(t *testing.T) {
+	raw := "POST / HTTP/1.1\nHost: example.com\nAuthorization: old\n\nbody\nAuthorization: body"
+	got := SetRawHeaders(raw, map[string]string{"Authorization": "new", "X-Token": "a"})
+	want := "POST / HTTP/1.1\nHost: example.com\nAuthorization: new\nX-Token: a\n\nbody\nAuthorization: body"
+	if got != want {
+		t.Errorf("Expect headers replaced before the body, got %q", got)
+	}
+
+	got = SetRawHeaders("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n", map[string]string{"Cookie": "a=b"})
+	if got != "GET / HTTP/1.1\r\nHost: example.com\r\nCookie: a=b\r\n\r\n" {
+		t.Errorf("Expect CRLF kept, got %q", got)
+	}
+}
@@ -2,30 +2,347 @@ package sender
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
-	"github.com/jaeles-project/jaeles/utils"
 	"log"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/debugger"
 	"github.com/chromedp/cdproto/dom"
+	"github.com/chromedp/cdproto/fetch"
 	"github.com/chromedp/cdproto/network"
 	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/cdproto/storage"
 	"github.com/chromedp/chromedp"
 	"github.com/jaeles-project/jaeles/libs"
+	"github.com/jaeles-project/jaeles/utils"
+)
+
+// headers chrome manage by itself
+var chromeSkipHeaders = map[string]bool{
+	"host":           true,
+	"content-length": true,
+	"cookie":         true,
+	"connection":     true,
+}
+
+// maximum browsers running at the same time, one browser is started for each proxy
+const maxChromeBrowsers = 4
+
+// chromeBrowser a browser kept alive for the whole scan
+type chromeBrowser struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	tabs    chan *chromeTab
+	mu      sync.Mutex
+	created int
+	max     int
+	// closed and replaced when a tab is gone, so waiting requests can open a new one
+	gone chan struct{}
+	// proxy the browser go through, auth is answered by the tabs since --proxy-server can't carry it
+	proxy string
+	auth  *url.Userinfo
+	// guarded by chromeMu, idle browser used least recently is closed first
+	users    int
+	lastUsed time.Time
+}
+
+// chromeTab a reusable tab, events are forwarded to the handler of the current request
+type chromeTab struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	mu      sync.Mutex
+	handler func(event interface{})
+	auth    *url.Userinfo
+	// requests that already got the proxy credentials, so wrong ones are not sent forever
+	authed map[fetch.RequestID]bool
+}
+
+var (
+	chromeMu   sync.Mutex
+	chromeCond = sync.NewCond(&chromeMu)
+	browsers   = make(map[string]*chromeBrowser)
 )
 
 // SendWithChrome send request with real browser
 func SendWithChrome(options libs.Options, req libs.Request) (libs.Response, error) {
-	// parsing some stuff
-	url := req.URL
-	// @TODO: parse more request component later
-	// method := req.Method
-	// body := req.Body
-	// headers := GetHeaders(req)
+	started := time.Now()
+	key := ReplayKey(req)
+	var res libs.Response
+	var err error
+	if options.ReplayDir != "" {
+		res, err = ReplayFixture(options, key, req)
+	} else {
+		res, err = sendWithChrome(options, req)
+	}
+	RecordHAR(options, req, res, err, started)
+	RecordFixture(options, key, req, res, err)
+	return res, err
+}
+
+func sendWithChrome(options libs.Options, req libs.Request) (libs.Response, error) {
+	if req.Method == "" {
+		req.Method = "GET"
+	}
 	if options.Verbose {
-		fmt.Printf("[Sent][Chrome] %v \n", url)
+		fmt.Printf("[Sent][Chrome] %v %v\n", req.Method, req.URL)
+	}
+
+	// waiting time for the page to load
+	waiting := time.Duration(1)
+	if req.Timeout != 0 {
+		waiting = time.Duration(req.Timeout)
 	}
+	var body string
+	var screenshot []byte
+	res, err := loadInChrome(options, req,
+		// wait for the page to load
+		chromedp.Sleep(waiting*time.Second),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			node, err := dom.GetDocument().Do(ctx)
+			if err != nil {
+				return err
+			}
+			body, err = dom.GetOuterHTML().WithNodeID(node.NodeID).Do(ctx)
+			return err
+		}),
+		// keep the page in case it's a finding, so it's never loaded again
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			var err error
+			if screenshot, err = page.CaptureScreenshot().Do(ctx); err != nil {
+				utils.DebugF("Error taking screenshot: %v", err)
+			}
+			return nil
+		}),
+	)
+	res.Body = body
+	res.Screenshot = screenshot
+	if err != nil {
+		return res, err
+	}
+	res.Beautify = fmt.Sprintf("%v\n%v\n", res.StatusCode, res.Body)
+	return res, nil
+}
+
+// loadInChrome load the request in a tab with its method, headers, body and cookies,
+// then run the actions on the page once its load event fired
+func loadInChrome(options libs.Options, req libs.Request, actions ...chromedp.Action) (libs.Response, error) {
 	var res libs.Response
+	if req.Method == "" {
+		req.Method = "GET"
+	}
+
+	proxy, pooled, err := RequestProxy(options, req)
+	if err != nil {
+		return res, err
+	}
+	browser, err := getBrowser(options, proxy, pooled)
+	if err != nil {
+		utils.ErrorF("Error starting chrome: %v", err)
+		return res, err
+	}
+	defer putBrowser(browser)
+	timeout := options.Timeout
+	if timeout <= 0 {
+		timeout = 20
+	}
+	tab, err := browser.acquire(time.Duration(timeout*2) * time.Second)
+	if err != nil {
+		utils.ErrorF("Error opening new tab: %v", err)
+		return res, err
+	}
+
+	ctx, cancel := context.WithTimeout(tab.ctx, time.Duration(timeout*2)*time.Second)
+	defer cancel()
+
+	// only modify the first document request of the main frame, not the sub resources
+	var mu sync.Mutex
+	var mainID network.RequestID
+	var intercepted bool
+	c := chromedp.FromContext(tab.ctx)
+	frameID := cdp.FrameID(c.Target.TargetID)
+	tab.setHandler(func(event interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		collectBrowserData(&res.Browser, event, frameID)
+		switch ev := event.(type) {
+		case *page.EventJavascriptDialogOpening:
+			utils.DebugF("Detecting Pop-up: %v", req.URL)
+			res.HasPopUp = true
+		case *fetch.EventRequestPaused:
+			params := fetch.ContinueRequest(ev.RequestID)
+			if !intercepted && ev.ResourceType == network.ResourceTypeDocument && ev.FrameID == frameID {
+				intercepted = true
+				params = chromeOverride(params, ev.Request, req)
+			}
+			go params.Do(cdp.WithExecutor(tab.ctx, c.Target))
+		case *network.EventRequestWillBeSent:
+			if mainID == "" && ev.Type == network.ResourceTypeDocument && ev.FrameID == frameID {
+				mainID = ev.RequestID
+			}
+		case *network.EventResponseReceived:
+			if ev.RequestID != mainID || ev.Response == nil {
+				return
+			}
+			res.StatusCode = int(ev.Response.Status)
+			res.Status = fmt.Sprintf("%v %v %v", ev.Response.Status, ev.Response.StatusText, strings.ToUpper(ev.Response.Protocol))
+			res.Headers = nil
+			var keys []string
+			for k := range ev.Response.Headers {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				res.Headers = append(res.Headers, map[string]string{k: fmt.Sprint(ev.Response.Headers[k])})
+			}
+		}
+	})
+
+	timeStart := time.Now()
+	err = chromedp.Run(ctx, append([]chromedp.Action{
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			// cookies from previous request shouldn't leak to this one
+			if err := storage.ClearCookies().WithBrowserContextID(c.BrowserContextID).
+				Do(cdp.WithExecutor(ctx, c.Browser)); err != nil {
+				return err
+			}
+			cookies := chromeCookies(req)
+			if len(cookies) == 0 {
+				return nil
+			}
+			return network.SetCookies(cookies).Do(ctx)
+		}),
+		// return after the load event of the page
+		chromedp.Navigate(req.URL),
+	}, actions...)...)
+	resTime := time.Since(timeStart).Seconds()
+
+	// leave the page so nothing keep running in the tab before reusing it
+	tab.setHandler(nil)
+	cleanCtx, cleanCancel := context.WithTimeout(tab.ctx, time.Duration(timeout)*time.Second)
+	cleanErr := chromedp.Run(cleanCtx, chromedp.Navigate("about:blank"))
+	cleanCancel()
+	browser.release(tab, cleanErr != nil)
+	if pooled {
+		proxyPool.Report(browser.proxy, err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	res.ResponseTime = resTime
+	if err != nil {
+		utils.ErrorF("%v", err)
+		return res, err
+	}
+	return res, nil
+}
+
+// chromeOverride apply method, body and headers of our request to the intercepted one
+func chromeOverride(params *fetch.ContinueRequestParams, original *network.Request, req libs.Request) *fetch.ContinueRequestParams {
+	headers := make(map[string]string)
+	if original != nil {
+		for k, v := range original.Headers {
+			headers[k] = fmt.Sprint(v)
+		}
+	}
+	for _, header := range req.Headers {
+		for k, v := range header {
+			if chromeSkipHeaders[strings.ToLower(k)] {
+				continue
+			}
+			for existing := range headers {
+				if strings.EqualFold(existing, k) {
+					delete(headers, existing)
+				}
+			}
+			headers[k] = v
+		}
+	}
+
+	var keys []string
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var entries []*fetch.HeaderEntry
+	for _, k := range keys {
+		entries = append(entries, &fetch.HeaderEntry{Name: k, Value: headers[k]})
+	}
+	params = params.WithHeaders(entries)
+
+	if method := NormalizeMethod(req.Method); method != "" && method != "GET" {
+		params = params.WithMethod(method)
+	}
+	if req.Body != "" {
+		params = params.WithPostData(base64.StdEncoding.EncodeToString([]byte(req.Body)))
+	}
+	return params
+}
+
+// chromeCookies get cookies from Cookie header and session of the request
+func chromeCookies(req libs.Request) []*network.CookieParam {
+	var cookies []*network.CookieParam
+	for _, header := range req.Headers {
+		for k, v := range header {
+			if !strings.EqualFold(k, "Cookie") {
+				continue
+			}
+			for _, item := range strings.Split(v, ";") {
+				parts := strings.SplitN(strings.TrimSpace(item), "=", 2)
+				if len(parts) != 2 || parts[0] == "" {
+					continue
+				}
+				cookies = append(cookies, &network.CookieParam{Name: parts[0], Value: parts[1], URL: req.URL})
+			}
+		}
+	}
+	if req.Jar != nil {
+		if u, err := url.Parse(req.URL); err == nil {
+			for _, cookie := range req.Jar.Cookies(u) {
+				cookies = append(cookies, &network.CookieParam{Name: cookie.Name, Value: cookie.Value, URL: req.URL})
+			}
+		}
+	}
+	return cookies
+}
+
+// getBrowser start a browser for the proxy or reuse the running one. When too many browsers are running,
+// the idle one used least recently is closed. If all of them are busy, request with proxy from the pool
+// go through any running browser of the pool, others wait for a browser to be free
+func getBrowser(options libs.Options, proxy string, pooled bool) (*chromeBrowser, error) {
+	server, auth, err := chromeProxy(proxy)
+	if err != nil {
+		return nil, err
+	}
+
+	chromeMu.Lock()
+	defer chromeMu.Unlock()
+	for {
+		if browser, ok := browsers[proxy]; ok {
+			browser.users++
+			return browser, nil
+		}
+		if len(browsers) < maxChromeBrowsers {
+			break
+		}
+		if idle := lruBrowser(true); idle != nil {
+			idle.cancel()
+			delete(browsers, idle.proxy)
+			continue
+		}
+		if pooled {
+			browser := lruBrowser(false)
+			browser.users++
+			return browser, nil
+		}
+		chromeCond.Wait()
+	}
 
 	isHeadless := true
 	if options.Debug {
@@ -41,108 +358,256 @@ func SendWithChrome(options libs.Options, req libs.Request) (libs.Response, erro
 		chromedp.Flag("disable-setuid-sandbox", true),
 		chromedp.Flag("no-first-run", true),
 		chromedp.Flag("no-default-browser-check", true),
-		chromedp.Flag("single-process", true),
 		chromedp.Flag("no-zygote", true),
 		chromedp.Flag("no-sandbox", true),
 	)
-
 	// proxy chrome headless
-	if options.Proxy != "" {
-		opts = append(opts, chromedp.ProxyServer(options.Proxy))
+	if server != "" {
+		opts = append(opts, chromedp.ProxyServer(server))
 	}
 
-	allocCtx, bcancel := chromedp.NewExecAllocator(context.Background(), opts...)
-	allocCtx, bcancel = context.WithTimeout(allocCtx, time.Duration(options.Timeout*2)*time.Second)
-	defer bcancel()
-	chromeContext, cancel := chromedp.NewContext(allocCtx, chromedp.WithLogf(log.Printf))
-	defer cancel()
+	allocCtx, allocCancel := chromedp.NewExecAllocator(context.Background(), opts...)
+	browserCtx, browserCancel := chromedp.NewContext(allocCtx, chromedp.WithLogf(log.Printf))
+	// start the browser
+	if err := chromedp.Run(browserCtx); err != nil {
+		browserCancel()
+		allocCancel()
+		return nil, err
+	}
 
-	// catch the pop up
-	chromedp.ListenTarget(chromeContext, func(event interface{}) {
-		if _, ok := event.(*page.EventJavascriptDialogOpening); ok {
-			// fmt.Println("closing alert:", ev.Message)
-			utils.DebugF("Detecting Pop-up: %v", url)
-			res.HasPopUp = true
-			go func() {
-				if err := chromedp.Run(chromeContext,
-					page.HandleJavaScriptDialog(true),
-				); err != nil {
-					res.HasPopUp = false
-				}
-			}()
+	max := options.ChromeTabs
+	if max <= 0 {
+		max = 5
+	}
+	browser := &chromeBrowser{
+		ctx: browserCtx,
+		cancel: func() {
+			browserCancel()
+			allocCancel()
+		},
+		tabs:  make(chan *chromeTab, max),
+		max:   max,
+		gone:  make(chan struct{}),
+		proxy: proxy,
+		auth:  auth,
+		users: 1,
+	}
+	browsers[proxy] = browser
+	return browser, nil
+}
+
+// putBrowser mark the browser as no longer used by the request
+func putBrowser(browser *chromeBrowser) {
+	chromeMu.Lock()
+	browser.users--
+	browser.lastUsed = time.Now()
+	chromeMu.Unlock()
+	chromeCond.Broadcast()
+}
+
+// lruBrowser get the running browser used least recently, only the ones no request is using when idle is set
+func lruBrowser(idle bool) *chromeBrowser {
+	var found *chromeBrowser
+	for _, browser := range browsers {
+		if idle && browser.users > 0 {
+			continue
+		}
+		if found == nil || browser.lastUsed.Before(found.lastUsed) {
+			found = browser
 		}
-	})
-	timeStart := time.Now()
-	// waiting time for the page to load
-	waiting := time.Duration(1)
-	if req.Timeout != 0 {
-		waiting = time.Duration(req.Timeout)
 	}
-	// start Chrome and run given tasks
-	err := chromedp.Run(
-		chromeContext,
-		chromeTask(chromeContext, url,
-			// @TODO: add header here
-			map[string]interface{}{},
-			&res),
-		// wait for the page to load
-		chromedp.Sleep(waiting*time.Second),
-		chromedp.ActionFunc(func(ctx context.Context) error {
-			node, err := dom.GetDocument().Do(ctx)
+	return found
+}
+
+// chromeProxy strip credentials from the proxy for --proxy-server,
+// chrome only answer auth challenge of HTTP proxy so SOCKS proxy with credentials is refused
+func chromeProxy(proxy string) (string, *url.Userinfo, error) {
+	if proxy == "" {
+		return "", nil, nil
+	}
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid proxy %v: %v", proxy, err)
+	}
+	auth := u.User
+	if auth == nil {
+		return proxy, nil, nil
+	}
+	if !isHTTPProxy(proxy) {
+		return "", nil, fmt.Errorf("chrome can't authenticate to %v proxy %v", u.Scheme, u.Host)
+	}
+	u.User = nil
+	return u.String(), auth, nil
+}
+
+// acquire get a free tab or open a new one until reaching the limit,
+// give up when the browser is closed or no tab is free before the timeout
+func (b *chromeBrowser) acquire(timeout time.Duration) (*chromeTab, error) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		select {
+		case tab := <-b.tabs:
+			return tab, nil
+		default:
+		}
+
+		b.mu.Lock()
+		if b.created < b.max {
+			b.created++
+			b.mu.Unlock()
+			tab, err := b.newTab()
 			if err != nil {
-				return err
+				b.tabGone()
 			}
-			res.Body, err = dom.GetOuterHTML().WithNodeID(node.NodeID).Do(ctx)
+			return tab, err
+		}
+		gone := b.gone
+		b.mu.Unlock()
+
+		select {
+		case tab := <-b.tabs:
+			return tab, nil
+		case <-gone:
+			// a tab couldn't be replaced, try to open one
+		case <-b.ctx.Done():
+			return nil, b.ctx.Err()
+		case <-deadline.C:
+			return nil, fmt.Errorf("no free tab after %v", timeout)
+		}
+	}
+}
+
+// tabGone free the place of a tab that couldn't be opened and wake up waiting requests
+func (b *chromeBrowser) tabGone() {
+	b.mu.Lock()
+	b.created--
+	close(b.gone)
+	b.gone = make(chan struct{})
+	b.mu.Unlock()
+}
+
+// release put the tab back to the pool or replace it when broken
+func (b *chromeBrowser) release(tab *chromeTab, broken bool) {
+	if !broken {
+		b.tabs <- tab
+		return
+	}
+	tab.cancel()
+	newTab, err := b.newTab()
+	if err != nil {
+		b.tabGone()
+		return
+	}
+	b.tabs <- newTab
+}
+
+// newTab open a tab in its own browser context so cookies are isolated
+func (b *chromeBrowser) newTab() (*chromeTab, error) {
+	ctx, cancel := chromedp.NewContext(b.ctx, chromedp.WithNewBrowserContext())
+	tab := &chromeTab{ctx: ctx, cancel: cancel, auth: b.auth, authed: make(map[fetch.RequestID]bool)}
+	chromedp.ListenTarget(ctx, tab.dispatch)
+	patterns := []*fetch.RequestPattern{
+		{URLPattern: "*", ResourceType: network.ResourceTypeDocument, RequestStage: fetch.RequestStageRequest},
+	}
+	if b.auth != nil {
+		// every request of the page go through the proxy and may need the credentials
+		patterns = []*fetch.RequestPattern{{URLPattern: "*", RequestStage: fetch.RequestStageRequest}}
+	}
+	err := chromedp.Run(ctx,
+		network.Enable(),
+		// report source of eval, debugger statements of the page shouldn't pause it
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			_, err := debugger.Enable().Do(ctx)
+			return err
+		}),
+		debugger.SetSkipAllPauses(true),
+		runtime.AddBinding(sinkBinding),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			_, err := page.AddScriptToEvaluateOnNewDocument(sinkHookScript).Do(ctx)
 			return err
 		}),
+		fetch.Enable().WithPatterns(patterns).WithHandleAuthRequests(b.auth != nil),
 	)
-	res.ResponseTime = time.Since(timeStart).Seconds()
 	if err != nil {
-		utils.ErrorF("%v", err)
-		return res, err
+		cancel()
+		return nil, err
 	}
+	return tab, nil
+}
 
-	res.Beautify = fmt.Sprintf("%v\n%v\n", res.StatusCode, res.Body)
-	return res, err
+func (t *chromeTab) setHandler(handler func(event interface{})) {
+	t.mu.Lock()
+	t.handler = handler
+	t.authed = make(map[fetch.RequestID]bool)
+	t.mu.Unlock()
 }
 
-// chrome debug protocol tasks to run
-func chromeTask(chromeContext context.Context, url string, requestHeaders map[string]interface{}, res *libs.Response) chromedp.Tasks {
-	// setup a listener for events
-	chromedp.ListenTarget(chromeContext, func(event interface{}) {
-		// get which type of event it is
-		switch msg := event.(type) {
-		// just before request sent
-		case *network.EventRequestWillBeSent:
-			request := msg.Request
-			// see if we have been redirected
-			// if so, change the URL that we are tracking
-			if msg.RedirectResponse != nil {
-				url = request.URL
-			}
+// authResponse answer auth challenge of the proxy with its credentials, only once for each request
+func (t *chromeTab) authResponse(ev *fetch.EventAuthRequired) *fetch.AuthChallengeResponse {
+	if t.auth == nil || ev.AuthChallenge == nil || ev.AuthChallenge.Source != fetch.AuthChallengeSourceProxy {
+		return &fetch.AuthChallengeResponse{Response: fetch.AuthChallengeResponseResponseDefault}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.authed[ev.RequestID] {
+		return &fetch.AuthChallengeResponse{Response: fetch.AuthChallengeResponseResponseCancelAuth}
+	}
+	t.authed[ev.RequestID] = true
+	password, _ := t.auth.Password()
+	return &fetch.AuthChallengeResponse{
+		Response: fetch.AuthChallengeResponseResponseProvideCredentials,
+		Username: t.auth.Username(),
+		Password: password,
+	}
+}
 
-		// once we have the full response
-		case *network.EventResponseReceived:
-			response := msg.Response
-			// is the request we want the status/headers on?
-			if response.URL == url {
-				res.StatusCode = int(response.Status)
-				// fmt.Printf(" url: %s\n", response.URL)
-				// fmt.Printf(" status code: %d\n", res.StatusCode)
-				for k, v := range response.Headers {
-					header := make(map[string]string)
-					// fmt.Println(k, v)
-					header[k] = v.(string)
-					res.Headers = append(res.Headers, header)
-				}
-			}
+// dispatch forward event to current request, keep the tab usable when nobody listening
+func (t *chromeTab) dispatch(event interface{}) {
+	t.mu.Lock()
+	handler := t.handler
+	t.mu.Unlock()
+
+	switch ev := event.(type) {
+	case *page.EventJavascriptDialogOpening:
+		go chromedp.Run(t.ctx, page.HandleJavaScriptDialog(true))
+	case *debugger.EventScriptParsed:
+		if handler != nil && isEvalScript(ev) {
+			go t.reportEval(handler, ev.ScriptID)
+		}
+		return
+	case *fetch.EventAuthRequired:
+		go fetch.ContinueWithAuth(ev.RequestID, t.authResponse(ev)).Do(cdp.WithExecutor(t.ctx, chromedp.FromContext(t.ctx).Target))
+		return
+	case *fetch.EventRequestPaused:
+		if handler == nil {
+			go fetch.ContinueRequest(ev.RequestID).Do(cdp.WithExecutor(t.ctx, chromedp.FromContext(t.ctx).Target))
+			return
 		}
+	}
+	if handler != nil {
+		handler(event)
+	}
+}
 
-	})
+// reportEval get source of the evaluated script and report it to the handler of the request
+func (t *chromeTab) reportEval(handler func(event interface{}), scriptID runtime.ScriptID) {
+	source, _, err := debugger.GetScriptSource(scriptID).Do(cdp.WithExecutor(t.ctx, chromedp.FromContext(t.ctx).Target))
+	if err != nil {
+		return
+	}
+	if event := evalSinkEvent(source); event != nil {
+		handler(event)
+	}
+}
 
-	return chromedp.Tasks{
-		network.Enable(),
-		network.SetExtraHTTPHeaders(network.Headers(requestHeaders)),
-		chromedp.Navigate(url),
+// CloseChrome close all browsers started during the scan
+func CloseChrome() {
+	chromeMu.Lock()
+	defer chromeMu.Unlock()
+	for proxy, browser := range browsers {
+		browser.cancel()
+		delete(browsers, proxy)
 	}
+	chromeCond.Broadcast()
 }
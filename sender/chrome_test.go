@@ -0,0 +1,143 @@
+package sender
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/debugger"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/jaeles-project/jaeles/libs"
+)
+
+func TestChromeOverride(t *testing.T) {
+	req := libs.Request{
+		Method: "post",
+		URL:    "http://example.com/login",
+		Body:   "user=admin",
+		Headers: []map[string]string{
+			{"user-agent": "jaeles"},
+			{"Content-Type": "application/x-www-form-urlencoded"},
+			{"Cookie": "sid=123; lang=en"},
+		},
+	}
+	original := &network.Request{Headers: network.Headers{"User-Agent": "HeadlessChrome"}}
+	params := chromeOverride(fetch.ContinueRequest("1"), original, req)
+
+	if params.Method != "POST" {
+		t.Errorf("Expect method to be overridden, got %v", params.Method)
+	}
+	if body, _ := base64.StdEncoding.DecodeString(params.PostData); string(body) != "user=admin" {
+		t.Errorf("Expect body to be overridden, got %v", string(body))
+	}
+	headers := make(map[string]string)
+	for _, header := range params.Headers {
+		headers[header.Name] = header.Value
+	}
+	if len(headers) != 2 || headers["user-agent"] != "jaeles" || headers["Content-Type"] == "" {
+		t.Errorf("Unexpected headers: %v", headers)
+	}
+
+	cookies := chromeCookies(req)
+	if len(cookies) != 2 || cookies[0].Name != "sid" || cookies[1].Value != "en" {
+		t.Errorf("Unexpected cookies: %v", cookies)
+	}
+}
+
+func TestCollectBrowserData(t *testing.T) {
+	var data libs.BrowserData
+	frameID := cdp.FrameID("main")
+	events := []interface{}{
+		&runtime.EventConsoleAPICalled{Type: runtime.APITypeLog, Args: []*runtime.RemoteObject{{Value: []byte(`"hello"`)}, {Value: []byte(`42`)}}},
+		&runtime.EventExceptionThrown{ExceptionDetails: &runtime.ExceptionDetails{Text: "Uncaught", Exception: &runtime.RemoteObject{Description: "ReferenceError: foo is not defined"}}},
+		&runtime.EventBindingCalled{Name: sinkBinding, Payload: `{"sink":"innerHTML","value":"<b>jaeles</b>"}`},
+		&runtime.EventBindingCalled{Name: "other", Payload: `{"sink":"eval","value":"x"}`},
+		&page.EventFrameNavigated{Frame: &cdp.Frame{ID: frameID, URL: "http://example.com/home"}},
+		&page.EventFrameNavigated{Frame: &cdp.Frame{ID: "iframe", URL: "http://ads.example.com/"}},
+		&network.EventRequestWillBeSent{FrameID: frameID, Type: network.ResourceTypeDocument,
+			Request:          &network.Request{Method: "GET", URL: "http://example.com/home"},
+			RedirectResponse: &network.Response{URL: "http://example.com/"}},
+		&network.EventRequestWillBeSent{FrameID: frameID, Type: network.ResourceTypeXHR,
+			Request: &network.Request{Method: "POST", URL: "http://example.com/api"}},
+	}
+	for _, event := range events {
+		collectBrowserData(&data, event, frameID)
+	}
+
+	if len(data.Console) != 1 || data.Console[0] != "[log] hello 42" {
+		t.Errorf("Unexpected console: %v", data.Console)
+	}
+	if len(data.Errors) != 1 || data.Errors[0] != "ReferenceError: foo is not defined" {
+		t.Errorf("Unexpected errors: %v", data.Errors)
+	}
+	if len(data.Sinks) != 1 || data.Sinks[0] != "innerHTML: <b>jaeles</b>" {
+		t.Errorf("Unexpected sinks: %v", data.Sinks)
+	}
+	if len(data.Navigations) != 2 || data.Navigations[1] != "http://example.com/ -> http://example.com/home" {
+		t.Errorf("Unexpected navigations: %v", data.Navigations)
+	}
+	if len(data.Requests) != 2 || data.Requests[1] != "POST http://example.com/api" {
+		t.Errorf("Unexpected requests: %v", data.Requests)
+	}
+}
+
+func TestEvalSink(t *testing.T) {
+	// direct eval keep its scope when it's not wrapped
+	if strings.Contains(sinkHookScript, "'eval'") {
+		t.Errorf("Expect eval not to be wrapped by the hook script")
+	}
+
+	stack := &runtime.StackTrace{CallFrames: []*runtime.CallFrame{{FunctionName: "run"}}}
+	if !isEvalScript(&debugger.EventScriptParsed{StackTrace: stack}) {
+		t.Errorf("Expect script compiled by the page to be eval")
+	}
+	if isEvalScript(&debugger.EventScriptParsed{URL: "http://example.com/app.js", StackTrace: stack}) {
+		t.Errorf("Expect script loaded by the page not to be eval")
+	}
+	if isEvalScript(&debugger.EventScriptParsed{}) {
+		t.Errorf("Expect script evaluated over the protocol not to be eval")
+	}
+
+	var data libs.BrowserData
+	collectBrowserData(&data, evalSinkEvent("alert(1)"), "main")
+	if len(data.Sinks) != 1 || data.Sinks[0] != "eval: alert(1)" {
+		t.Errorf("Unexpected sinks: %v", data.Sinks)
+	}
+	if evalSinkEvent("(function anonymous(\n) {\nalert(1)\n})") != nil {
+		t.Errorf("Expect Function constructor not to be reported twice")
+	}
+}
+
+func TestChromeAcquire(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	// every tab is in use
+	browser := &chromeBrowser{ctx: ctx, cancel: cancel, tabs: make(chan *chromeTab, 1), created: 1, max: 1, gone: make(chan struct{})}
+
+	if _, err := browser.acquire(50 * time.Millisecond); err == nil {
+		t.Errorf("Expect timeout when no tab is free")
+	}
+
+	// broken tab couldn't be replaced, waiting requests wake up and can open one
+	gone := browser.gone
+	browser.tabGone()
+	select {
+	case <-gone:
+	default:
+		t.Errorf("Expect waiting requests to be woken up when a tab is gone")
+	}
+	if browser.created != 0 {
+		t.Errorf("Expect place of the gone tab to be free, got %v", browser.created)
+	}
+
+	browser.created = browser.max
+	cancel()
+	if _, err := browser.acquire(time.Minute); err == nil {
+		t.Errorf("Expect closed browser to stop waiting")
+	}
+}
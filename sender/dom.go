@@ -0,0 +1,139 @@
+package sender
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/debugger"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/jaeles-project/jaeles/libs"
+)
+
+// name of the binding that hooked sinks call to report back
+const sinkBinding = "__jaelesSink"
+
+// script run before any script of the page, wrap dangerous sinks to report their input.
+// eval is not wrapped since direct eval called through a wrapper lose the local scope, it's reported from the debugger
+const sinkHookScript = `(function () {
+  var report = window.__jaelesSink;
+  if (!report || window.__jaelesHooked) { return; }
+  window.__jaelesHooked = true;
+  var send = function (sink, value) {
+    try { report(JSON.stringify({sink: sink, value: String(value).slice(0, 2048)})); } catch (e) {}
+  };
+  var wrap = function (obj, name, sink) {
+    var original = obj[name];
+    if (typeof original !== 'function') { return; }
+    obj[name] = function () {
+      if (arguments.length > 0 && (sink !== 'setTimeout' && sink !== 'setInterval' || typeof arguments[0] === 'string')) {
+        send(sink, Array.prototype.join.call(arguments, ''));
+      }
+      return original.apply(this, arguments);
+    };
+  };
+  var wrapSetter = function (proto, name) {
+    var desc = Object.getOwnPropertyDescriptor(proto, name);
+    if (!desc || !desc.set) { return; }
+    Object.defineProperty(proto, name, {
+      configurable: true, enumerable: desc.enumerable, get: desc.get,
+      set: function (value) { send(name, value); return desc.set.call(this, value); }
+    });
+  };
+  wrap(window, 'setTimeout', 'setTimeout');
+  wrap(window, 'setInterval', 'setInterval');
+  wrap(document, 'write', 'document.write');
+  wrap(document, 'writeln', 'document.writeln');
+  wrap(Element.prototype, 'insertAdjacentHTML', 'insertAdjacentHTML');
+  wrap(Element.prototype, 'setAttribute', 'setAttribute');
+  wrapSetter(Element.prototype, 'innerHTML');
+  wrapSetter(Element.prototype, 'outerHTML');
+  var OriginalFunction = window.Function;
+  window.Function = function () {
+    send('Function', Array.prototype.join.call(arguments, ','));
+    return OriginalFunction.apply(this, arguments);
+  };
+  window.Function.prototype = OriginalFunction.prototype;
+})();`
+
+// isEvalScript check if the script was compiled from a string by a script of the page like eval does,
+// scripts loaded by the page have URL and the ones we evaluate over the protocol have no stack
+func isEvalScript(ev *debugger.EventScriptParsed) bool {
+	return ev.URL == "" && ev.StackTrace != nil
+}
+
+// evalSinkEvent report source of eval like the hooked sinks do, Function constructor is already reported by the hook
+func evalSinkEvent(source string) *runtime.EventBindingCalled {
+	if strings.HasPrefix(source, "(function anonymous(") {
+		return nil
+	}
+	if len(source) > 2048 {
+		source = source[:2048]
+	}
+	payload, _ := json.Marshal(map[string]string{"sink": "eval", "value": source})
+	return &runtime.EventBindingCalled{Name: sinkBinding, Payload: string(payload)}
+}
+
+// collectBrowserData store DOM events of the main frame to the response
+func collectBrowserData(data *libs.BrowserData, event interface{}, frameID cdp.FrameID) {
+	switch ev := event.(type) {
+	case *runtime.EventConsoleAPICalled:
+		var args []string
+		for _, arg := range ev.Args {
+			args = append(args, remoteObjectString(arg))
+		}
+		data.Console = append(data.Console, fmt.Sprintf("[%v] %v", ev.Type, strings.Join(args, " ")))
+	case *runtime.EventExceptionThrown:
+		if details := ev.ExceptionDetails; details != nil {
+			message := details.Text
+			if details.Exception != nil && details.Exception.Description != "" {
+				message = details.Exception.Description
+			}
+			data.Errors = append(data.Errors, message)
+		}
+	case *runtime.EventBindingCalled:
+		if ev.Name != sinkBinding {
+			return
+		}
+		var hit struct {
+			Sink  string
+			Value string
+		}
+		if err := json.Unmarshal([]byte(ev.Payload), &hit); err == nil {
+			data.Sinks = append(data.Sinks, fmt.Sprintf("%v: %v", hit.Sink, hit.Value))
+		}
+	case *page.EventFrameNavigated:
+		if ev.Frame != nil && ev.Frame.ID == frameID {
+			data.Navigations = append(data.Navigations, ev.Frame.URL)
+		}
+	case *page.EventNavigatedWithinDocument:
+		if ev.FrameID == frameID {
+			data.Navigations = append(data.Navigations, ev.URL)
+		}
+	case *network.EventRequestWillBeSent:
+		if ev.Request == nil {
+			return
+		}
+		if ev.RedirectResponse != nil && ev.FrameID == frameID && ev.Type == network.ResourceTypeDocument {
+			data.Navigations = append(data.Navigations, fmt.Sprintf("%v -> %v", ev.RedirectResponse.URL, ev.Request.URL))
+		}
+		data.Requests = append(data.Requests, fmt.Sprintf("%v %v", ev.Request.Method, ev.Request.URL))
+	}
+}
+
+func remoteObjectString(obj *runtime.RemoteObject) string {
+	if obj == nil {
+		return ""
+	}
+	if len(obj.Value) > 0 {
+		var value interface{}
+		if err := json.Unmarshal(obj.Value, &value); err == nil {
+			return fmt.Sprint(value)
+		}
+		return string(obj.Value)
+	}
+	return obj.Description
+}
@@ -0,0 +1,246 @@
+package sender
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jaeles-project/jaeles/libs"
+	"github.com/jaeles-project/jaeles/utils"
+)
+
+// HAR 1.2 format, see http://www.softwareishard.com/blog/har-12-spec/
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	SignatureID     string      `json:"_signatureId,omitempty"`
+	ScanID          string      `json:"_scanId,omitempty"`
+	Engine          string      `json:"_engine,omitempty"`
+	Error           string      `json:"_error,omitempty"`
+}
+
+type harRequest struct {
+	Method      string       `json:"method"`
+	URL         string       `json:"url"`
+	HTTPVersion string       `json:"httpVersion"`
+	Cookies     []harPair    `json:"cookies"`
+	Headers     []harPair    `json:"headers"`
+	QueryString []harPair    `json:"queryString"`
+	PostData    *harPostData `json:"postData,omitempty"`
+	HeadersSize int          `json:"headersSize"`
+	BodySize    int          `json:"bodySize"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harResponse struct {
+	Status      int        `json:"status"`
+	StatusText  string     `json:"statusText"`
+	HTTPVersion string     `json:"httpVersion"`
+	Cookies     []harPair  `json:"cookies"`
+	Headers     []harPair  `json:"headers"`
+	Content     harContent `json:"content"`
+	RedirectURL string     `json:"redirectURL"`
+	HeadersSize int        `json:"headersSize"`
+	BodySize    int        `json:"bodySize"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harPair struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// HARWriter stream entries to HAR file
+type HARWriter struct {
+	mu      sync.Mutex
+	file    *os.File
+	entries int
+}
+
+var harWriter *HARWriter
+
+// InitHAR create HAR file and write the header
+func InitHAR(harFile string) error {
+	file, err := os.Create(utils.NormalizePath(harFile))
+	if err != nil {
+		return err
+	}
+	header := fmt.Sprintf(`{"log":{"version":"1.2","creator":{"name":"jaeles","version":%q},"entries":[`, libs.VERSION)
+	if _, err := file.WriteString(header); err != nil {
+		file.Close()
+		return err
+	}
+	harWriter = &HARWriter{file: file}
+	return nil
+}
+
+// CloseHAR close entries list so the file is valid JSON
+func CloseHAR() {
+	if harWriter == nil {
+		return
+	}
+	harWriter.mu.Lock()
+	defer harWriter.mu.Unlock()
+	harWriter.file.WriteString("\n]}}\n")
+	harWriter.file.Close()
+	utils.InforF("HAR file written with %v entries: %v", harWriter.entries, harWriter.file.Name())
+	harWriter = nil
+}
+
+// RecordHAR append request and response to HAR file if enabled
+func RecordHAR(options libs.Options, req libs.Request, res libs.Response, err error, started time.Time) {
+	if harWriter == nil {
+		return
+	}
+	entry := harEntry{
+		StartedDateTime: started.Format(time.RFC3339Nano),
+		Time:            float64(time.Since(started).Milliseconds()),
+		Request:         harRequestOf(req),
+		Response:        harResponseOf(res),
+		SignatureID:     req.SignID,
+		ScanID:          options.ScanID,
+		Engine:          req.Engine,
+	}
+	entry.Timings.Wait = entry.Time
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	data, jerr := json.Marshal(entry)
+	if jerr != nil {
+		utils.ErrorF("Error encoding HAR entry: %v", jerr)
+		return
+	}
+	harWriter.mu.Lock()
+	defer harWriter.mu.Unlock()
+	if harWriter.entries > 0 {
+		harWriter.file.WriteString(",")
+	}
+	harWriter.file.WriteString("\n")
+	harWriter.file.Write(data)
+	harWriter.entries++
+}
+
+func harRequestOf(req libs.Request) harRequest {
+	headers := req.Headers
+	body := req.Body
+	// raw engine only have the raw request
+	if req.Engine == "raw" && req.Raw != "" {
+		headers, body = splitRaw(req.Raw)
+	}
+
+	harReq := harRequest{
+		Method:      req.Method,
+		URL:         req.URL,
+		HTTPVersion: "HTTP/1.1",
+		Cookies:     []harPair{},
+		Headers:     harPairs(headers),
+		QueryString: []harPair{},
+		HeadersSize: -1,
+		BodySize:    len(body),
+	}
+	if u, err := url.Parse(req.URL); err == nil {
+		for k, values := range u.Query() {
+			for _, v := range values {
+				harReq.QueryString = append(harReq.QueryString, harPair{Name: k, Value: v})
+			}
+		}
+	}
+	if body != "" {
+		harReq.PostData = &harPostData{
+			MimeType: pairValue(harReq.Headers, "Content-Type"),
+			Text:     body,
+		}
+	}
+	return harReq
+}
+
+func harResponseOf(res libs.Response) harResponse {
+	harRes := harResponse{
+		Status:      res.StatusCode,
+		HTTPVersion: "HTTP/1.1",
+		Cookies:     []harPair{},
+		Headers:     harPairs(res.Headers),
+		HeadersSize: -1,
+		BodySize:    len(res.Body),
+	}
+	// status is stored as '200 OK HTTP/1.1'
+	if parts := strings.Fields(res.Status); len(parts) > 1 {
+		if strings.HasPrefix(parts[len(parts)-1], "HTTP/") {
+			harRes.HTTPVersion = parts[len(parts)-1]
+			parts = parts[:len(parts)-1]
+		}
+		harRes.StatusText = strings.Join(parts[1:], " ")
+	}
+	harRes.RedirectURL = pairValue(harRes.Headers, "Location")
+	harRes.Content = harContent{
+		Size:     len(res.Body),
+		MimeType: pairValue(harRes.Headers, "Content-Type"),
+		Text:     res.Body,
+	}
+	return harRes
+}
+
+// harPairs convert headers to HAR format, skip the pseudo headers we added
+func harPairs(headers []map[string]string) []harPair {
+	pairs := []harPair{}
+	for _, header := range headers {
+		for k, v := range header {
+			if k == "Total Length" || k == "Response Time" {
+				continue
+			}
+			pairs = append(pairs, harPair{Name: k, Value: v})
+		}
+	}
+	return pairs
+}
+
+func pairValue(pairs []harPair, name string) string {
+	for _, pair := range pairs {
+		if strings.EqualFold(pair.Name, name) {
+			return pair.Value
+		}
+	}
+	return ""
+}
+
+// splitRaw get headers and body from raw request
+func splitRaw(raw string) ([]map[string]string, string) {
+	var headers []map[string]string
+	raw = strings.ReplaceAll(raw, "\r\n", "\n")
+	head, body := raw, ""
+	if index := strings.Index(raw, "\n\n"); index >= 0 {
+		head, body = raw[:index], raw[index+2:]
+	}
+	for _, line := range strings.Split(head, "\n")[1:] {
+		if index := strings.Index(line, ":"); index > 0 {
+			headers = append(headers, map[string]string{
+				strings.TrimSpace(line[:index]): strings.TrimSpace(line[index+1:]),
+			})
+		}
+	}
+	return headers, body
+}
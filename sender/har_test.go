@@ -0,0 +1,59 @@
+package sender
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/jaeles-project/jaeles/libs"
+)
+
+func TestRecordHAR(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("hello"))
+	}))
+	defer ts.Close()
+
+	harFile := filepath.Join(t.TempDir(), "out.har")
+	if err := InitHAR(harFile); err != nil {
+		t.Fatalf("Error creating HAR: %v", err)
+	}
+
+	var options libs.Options
+	options.Timeout = 5
+	options.ScanID = "scan-01"
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			JustSend(options, libs.Request{Method: "POST", URL: ts.URL + "/?q=1", Body: "a=b", SignID: "sign-01"})
+		}()
+	}
+	wg.Wait()
+	CloseHAR()
+
+	data, _ := os.ReadFile(harFile)
+	var har struct {
+		Log struct {
+			Version string
+			Entries []harEntry
+		}
+	}
+	if err := json.Unmarshal(data, &har); err != nil {
+		t.Fatalf("Invalid HAR file: %v", err)
+	}
+	if har.Log.Version != "1.2" || len(har.Log.Entries) != 10 {
+		t.Fatalf("Expect 10 entries, got %v", len(har.Log.Entries))
+	}
+	entry := har.Log.Entries[0]
+	if entry.SignatureID != "sign-01" || entry.ScanID != "scan-01" || entry.Response.Content.Text != "hello" ||
+		entry.Request.PostData == nil || entry.Request.QueryString[0].Value != "1" {
+		t.Errorf("Unexpected HAR entry: %+v", entry)
+	}
+}
@@ -0,0 +1,436 @@
+package sender
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jaeles-project/jaeles/libs"
+	"github.com/jaeles-project/jaeles/utils"
+)
+
+const (
+	maxBackoff    = 60 * time.Second
+	maxRetryAfter = 5 * time.Minute
+)
+
+// hostLimiter token bucket for a single host
+type hostLimiter struct {
+	mu         sync.Mutex
+	baseRate   float64
+	rate       float64
+	tokens     float64
+	last       time.Time
+	blockUntil time.Time
+	backoff    int
+}
+
+var (
+	limiterMu sync.Mutex
+	limiters  = make(map[string]*hostLimiter)
+)
+
+// LimiterEnabled check if per host rate limit is enabled
+func LimiterEnabled(options libs.Options) bool {
+	return options.RateLimit > 0 || options.Delay > 0
+}
+
+// limiterRate get requests per second allowed for a single host
+func limiterRate(options libs.Options) float64 {
+	rate := float64(options.RateLimit)
+	if options.Delay > 0 {
+		delayRate := 1 / float64(options.Delay)
+		if rate == 0 || delayRate < rate {
+			rate = delayRate
+		}
+	}
+	return rate
+}
+
+// lookupLimiter get limiter of the host without creating it
+func lookupLimiter(host string) *hostLimiter {
+	limiterMu.Lock()
+	defer limiterMu.Unlock()
+	return limiters[host]
+}
+
+func getLimiter(options libs.Options, host string) *hostLimiter {
+	limiterMu.Lock()
+	defer limiterMu.Unlock()
+	if limiter, ok := limiters[host]; ok {
+		return limiter
+	}
+	rate := limiterRate(options)
+	limiter := &hostLimiter{
+		baseRate: rate,
+		rate:     rate,
+		tokens:   1,
+		last:     time.Now(),
+	}
+	limiters[host] = limiter
+	return limiter
+}
+
+// limiterHost get host:port as limiter key
+func limiterHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	return u.Host
+}
+
+// WaitHost block until the host allow sending another request
+func WaitHost(options libs.Options, rawURL string) {
+	if rawURL == "" {
+		return
+	}
+	host := limiterHost(rawURL)
+	// without rate limit only host that was throttled has a limiter
+	limiter := lookupLimiter(host)
+	if LimiterEnabled(options) {
+		limiter = getLimiter(options, host)
+	}
+	if limiter == nil {
+		return
+	}
+	for {
+		wait := limiter.reserve()
+		if wait <= 0 {
+			return
+		}
+		utils.DebugF("[Throttle] %v wait %v", host, wait)
+		time.Sleep(wait)
+	}
+}
+
+// reserve take a token or return the time to wait for the next one
+func (l *hostLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if now.Before(l.blockUntil) {
+		return l.blockUntil.Sub(now)
+	}
+	if l.rate <= 0 {
+		return 0
+	}
+
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > 1 {
+		l.tokens = 1
+	}
+	l.last = now
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+	return time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+}
+
+// ThrottleHost adapt the limiter of the host based on the response,
+// backoff always apply even when rate limit is not set
+func ThrottleHost(options libs.Options, rawURL string, res libs.Response) {
+	if rawURL == "" || res.StatusCode == 0 {
+		return
+	}
+	host := limiterHost(rawURL)
+	throttled := res.StatusCode == http.StatusTooManyRequests || res.StatusCode == http.StatusServiceUnavailable
+	if !throttled && !LimiterEnabled(options) && lookupLimiter(host) == nil {
+		return
+	}
+	limiter := getLimiter(options, host)
+
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	if !throttled {
+		// slowly recover after backing off
+		if limiter.backoff > 0 {
+			limiter.backoff--
+		}
+		if limiter.rate < limiter.baseRate {
+			limiter.rate = math.Min(limiter.baseRate, limiter.rate*1.1)
+		}
+		return
+	}
+
+	limiter.backoff++
+	wait := time.Duration(math.Min(math.Pow(2, float64(limiter.backoff)), maxBackoff.Seconds())) * time.Second
+	if retryAfter, ok := parseRetryAfter(GetHeader(res, "Retry-After")); ok {
+		wait = retryAfter
+	}
+	limiter.blockUntil = time.Now().Add(wait)
+	// host without rate limit only pause, rate limit under the floor is never raised
+	if limiter.baseRate > 0 {
+		limiter.rate = math.Min(limiter.baseRate, math.Max(limiter.rate/2, 0.1))
+	}
+
+	if options.Verbose {
+		fmt.Printf("[Throttle] %v got %v -- pause %v, rate %.2f req/s\n", host, res.StatusCode, wait, limiter.rate)
+	}
+}
+
+// parseRetryAfter parse Retry-After header in seconds or HTTP date format
+func parseRetryAfter(value string) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	var wait time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		wait = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(value); err == nil {
+		wait = time.Until(date)
+	} else {
+		return 0, false
+	}
+	if wait < 0 {
+		wait = 0
+	}
+	if wait > maxRetryAfter {
+		wait = maxRetryAfter
+	}
+	return wait, true
+}
+
+// GetHeader get response header value by name
+func GetHeader(res libs.Response, name string) string {
+	for _, header := range res.Headers {
+		for key, value := range header {
+			if strings.EqualFold(key, name) {
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+var (
+	slotMu    sync.Mutex
+	hostSlots = make(map[string]chan struct{})
+	ipSlots   = make(map[string]chan struct{})
+	hostIPs   = make(map[string]string)
+)
+
+// AcquireHost wait for a free slot of the host and its IP, return function to release them.
+// Request of a job that the scheduler reserved a slot for use that slot instead
+func AcquireHost(options libs.Options, rawURL string) func() {
+	if options.Slot != nil {
+		if release, ok := options.Slot.Take(rawURL); ok {
+			return release
+		}
+	}
+	slots := limitedSlots(options, rawURL)
+	for _, slot := range slots {
+		slot <- struct{}{}
+	}
+	return releaseSlots(slots)
+}
+
+// TryAcquireHost take free slots of the host and its IP without waiting, nothing is taken if one of them is full
+func TryAcquireHost(options libs.Options, rawURL string) (func(), bool) {
+	return tryAcquire(limitedSlots(options, rawURL))
+}
+
+func tryAcquire(slots []chan struct{}) (func(), bool) {
+	for index, slot := range slots {
+		select {
+		case slot <- struct{}{}:
+		default:
+			for _, taken := range slots[:index] {
+				<-taken
+			}
+			return nil, false
+		}
+	}
+	return releaseSlots(slots), true
+}
+
+// limitedSlots get slots of the host and its IP that have a limit
+func limitedSlots(options libs.Options, rawURL string) []chan struct{} {
+	if (options.MaxPerHost <= 0 && options.MaxPerIP <= 0) || rawURL == "" {
+		return nil
+	}
+	host := limiterHost(rawURL)
+	var slots []chan struct{}
+	if options.MaxPerHost > 0 {
+		slots = append(slots, getSlot(hostSlots, host, options.MaxPerHost))
+	}
+	if options.MaxPerIP > 0 {
+		if ip := resolveHost(host); ip != "" {
+			slots = append(slots, getSlot(ipSlots, ip, options.MaxPerIP))
+		}
+	}
+	return slots
+}
+
+func releaseSlots(slots []chan struct{}) func() {
+	return func() {
+		for _, slot := range slots {
+			<-slot
+		}
+		if len(slots) > 0 {
+			notifyRelease()
+		}
+	}
+}
+
+// HostSlot slots of a host and its IP reserved for a whole job by the scheduler.
+// Requests of the job to that host take turns on the reserved slot, extra free slots are used when there are
+type HostSlot struct {
+	options libs.Options
+	slots   []chan struct{}
+	// one request of the job at a time use the reserved slot
+	turn    chan struct{}
+	release func()
+	once    sync.Once
+}
+
+// ReserveHost take free slots of the host and its IP for a job without waiting,
+// nil slot is returned when the host has no limit
+func ReserveHost(options libs.Options, rawURL string) (*HostSlot, bool) {
+	slots := limitedSlots(options, rawURL)
+	if len(slots) == 0 {
+		return nil, true
+	}
+	release, ok := tryAcquire(slots)
+	if !ok {
+		return nil, false
+	}
+	slot := &HostSlot{
+		options: options,
+		slots:   slots,
+		turn:    make(chan struct{}, 1),
+		release: release,
+	}
+	slot.turn <- struct{}{}
+	return slot, true
+}
+
+// Take use the reserved slot for a request to the URL, ok is false when the URL is on another host and IP
+func (h *HostSlot) Take(rawURL string) (func(), bool) {
+	if h == nil {
+		return nil, false
+	}
+	var covered bool
+	var rest []chan struct{}
+	for _, slot := range limitedSlots(h.options, rawURL) {
+		if h.covers(slot) {
+			covered = true
+			continue
+		}
+		rest = append(rest, slot)
+	}
+	if !covered {
+		return nil, false
+	}
+
+	select {
+	case <-h.turn:
+	default:
+		// another request of the job is using it, go with a free slot if there is
+		if release, ok := TryAcquireHost(h.options, rawURL); ok {
+			return release, true
+		}
+		<-h.turn
+	}
+	for _, slot := range rest {
+		slot <- struct{}{}
+	}
+	releaseRest := releaseSlots(rest)
+	return func() {
+		releaseRest()
+		h.turn <- struct{}{}
+	}, true
+}
+
+func (h *HostSlot) covers(slot chan struct{}) bool {
+	for _, reserved := range h.slots {
+		if reserved == slot {
+			return true
+		}
+	}
+	return false
+}
+
+// Release give the reserved slots back once the job is done
+func (h *HostSlot) Release() {
+	if h == nil {
+		return
+	}
+	h.once.Do(h.release)
+}
+
+var (
+	listenerMu       sync.Mutex
+	releaseListeners = make(map[int]func())
+	listenerID       int
+)
+
+// OnRelease call the function every time a slot is released, return function to stop it
+func OnRelease(listener func()) func() {
+	listenerMu.Lock()
+	defer listenerMu.Unlock()
+	listenerID++
+	id := listenerID
+	releaseListeners[id] = listener
+	return func() {
+		listenerMu.Lock()
+		delete(releaseListeners, id)
+		listenerMu.Unlock()
+	}
+}
+
+func notifyRelease() {
+	listenerMu.Lock()
+	var listeners []func()
+	for _, listener := range releaseListeners {
+		listeners = append(listeners, listener)
+	}
+	listenerMu.Unlock()
+	for _, listener := range listeners {
+		listener()
+	}
+}
+
+func getSlot(slots map[string]chan struct{}, key string, size int) chan struct{} {
+	slotMu.Lock()
+	defer slotMu.Unlock()
+	if slot, ok := slots[key]; ok {
+		return slot
+	}
+	slot := make(chan struct{}, size)
+	slots[key] = slot
+	return slot
+}
+
+// resolveHost get IP of the host, cached for the whole scan
+func resolveHost(host string) string {
+	hostname := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		hostname = h
+	}
+	if ip := net.ParseIP(hostname); ip != nil {
+		return ip.String()
+	}
+
+	slotMu.Lock()
+	ip, ok := hostIPs[hostname]
+	slotMu.Unlock()
+	if ok {
+		return ip
+	}
+	if ips, err := net.LookupIP(hostname); err == nil && len(ips) > 0 {
+		ip = ips[0].String()
+	}
+	slotMu.Lock()
+	hostIPs[hostname] = ip
+	slotMu.Unlock()
+	return ip
+}
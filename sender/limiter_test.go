@@ -0,0 +1,165 @@
+package sender
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jaeles-project/jaeles/libs"
+)
+
+func TestWaitHost(t *testing.T) {
+	var options libs.Options
+	options.RateLimit = 10
+	url := "http://limiter.example.com/"
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		WaitHost(options, url)
+	}
+	// first request go right away, the other three wait ~100ms each
+	if elapsed := time.Since(start); elapsed < 250*time.Millisecond {
+		t.Errorf("Expect rate limit to slow down requests, took %v", elapsed)
+	}
+
+	// other host shouldn't be affected
+	start = time.Now()
+	WaitHost(options, "http://other.example.com/")
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("Expect other host not to wait, took %v", elapsed)
+	}
+}
+
+func TestThrottleHost(t *testing.T) {
+	var options libs.Options
+	options.RateLimit = 100
+	url := "http://throttle.example.com/"
+
+	var res libs.Response
+	res.StatusCode = 429
+	res.Headers = append(res.Headers, map[string]string{"Retry-After": "1"})
+	WaitHost(options, url)
+	ThrottleHost(options, url, res)
+
+	start := time.Now()
+	WaitHost(options, url)
+	if elapsed := time.Since(start); elapsed < 900*time.Millisecond {
+		t.Errorf("Expect to respect Retry-After, took %v", elapsed)
+	}
+
+	// backoff without rate limit
+	url = "http://backoff.example.com/"
+	res.Headers = nil
+	res.StatusCode = 503
+	ThrottleHost(libs.Options{}, url, res)
+	start = time.Now()
+	WaitHost(libs.Options{}, url)
+	if elapsed := time.Since(start); elapsed < 1900*time.Millisecond {
+		t.Errorf("Expect automatic backoff without rate limit, took %v", elapsed)
+	}
+	res.StatusCode = 200
+	ThrottleHost(libs.Options{}, url, res)
+	start = time.Now()
+	WaitHost(libs.Options{}, url)
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("Expect no rate limit after backoff, took %v", elapsed)
+	}
+
+	if wait, ok := parseRetryAfter("120"); !ok || wait != 2*time.Minute {
+		t.Errorf("Error parsing Retry-After: %v", wait)
+	}
+	if _, ok := parseRetryAfter("soon"); ok {
+		t.Errorf("Expect invalid Retry-After to be ignored")
+	}
+}
+
+func TestThrottleSlowRate(t *testing.T) {
+	// one request every 20 seconds is 0.05 req/s, below the floor of throttled rate
+	var options libs.Options
+	options.Delay = 20
+	url := "http://slow-rate.example.com/"
+
+	var res libs.Response
+	res.StatusCode = 429
+	res.Headers = append(res.Headers, map[string]string{"Retry-After": "0"})
+	ThrottleHost(options, url, res)
+	limiter := lookupLimiter(limiterHost(url))
+	if limiter == nil {
+		t.Fatalf("Expect limiter of the throttled host")
+	}
+	if limiter.rate > 0.05 {
+		t.Errorf("Expect throttled rate not to exceed the configured rate, got %v", limiter.rate)
+	}
+}
+
+func TestAcquireHost(t *testing.T) {
+	var options libs.Options
+	options.MaxPerHost = 2
+	url := "http://127.0.0.1:8000/"
+
+	var inFlight, maxInFlight int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release := AcquireHost(options, url)
+			current := atomic.AddInt32(&inFlight, 1)
+			for {
+				max := atomic.LoadInt32(&maxInFlight)
+				if current <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, current) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			atomic.AddInt32(&inFlight, -1)
+			release()
+		}()
+	}
+	wg.Wait()
+	if maxInFlight > 2 {
+		t.Errorf("Expect at most 2 in-flight requests, got %v", maxInFlight)
+	}
+}
+
+func TestReserveHost(t *testing.T) {
+	var options libs.Options
+	options.MaxPerHost = 2
+	url := "http://reserve.example.com/"
+
+	slot, ok := ReserveHost(options, url)
+	if !ok || slot == nil {
+		t.Fatalf("Expect to reserve a slot of free host")
+	}
+	jobOptions := options
+	jobOptions.Slot = slot
+
+	// first request use the reserved slot, second one of the job the free slot left
+	first := AcquireHost(jobOptions, url)
+	second, ok := slot.Take(url)
+	if !ok {
+		t.Fatalf("Expect reserved slot to cover its host")
+	}
+	if release, ok := TryAcquireHost(options, url); ok {
+		t.Errorf("Expect the host to be full")
+		release()
+	}
+	second()
+	first()
+
+	// other host isn't covered by the reservation
+	if _, ok := slot.Take("http://other-reserve.example.com/"); ok {
+		t.Errorf("Expect reserved slot not to cover other host")
+	}
+
+	slot.Release()
+	slot.Release()
+	for i := 0; i < 2; i++ {
+		reserved, ok := ReserveHost(options, url)
+		if !ok {
+			t.Errorf("Expect released slot to be free again")
+		}
+		defer reserved.Release()
+	}
+}
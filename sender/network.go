@@ -0,0 +1,130 @@
+package sender
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/jaeles-project/jaeles/libs"
+	"github.com/jaeles-project/jaeles/utils"
+)
+
+// SendNetwork connect to host:port, send data and read until timeout or delimiter
+func SendNetwork(options libs.Options, nw libs.Network) (libs.Response, error) {
+	req := NetworkRequest(nw)
+	started := time.Now()
+	key := ReplayKey(req)
+	var res libs.Response
+	var err error
+	if options.ReplayDir != "" {
+		res, err = ReplayFixture(options, key, req)
+	} else {
+		res, err = sendNetwork(options, nw, req)
+	}
+	RecordHAR(options, req, res, err, started)
+	RecordFixture(options, key, req, res, err)
+	return res, err
+}
+
+// NetworkRequest describe network part as request for proxy, replay and HAR
+func NetworkRequest(nw libs.Network) libs.Request {
+	return libs.Request{
+		Engine:  "network",
+		URL:     NetworkURL(nw),
+		Body:    nw.Data,
+		Timeout: nw.Timeout,
+	}
+}
+
+// NetworkURL describe where we connected to
+func NetworkURL(nw libs.Network) string {
+	scheme := "tcp"
+	if nw.TLS {
+		scheme = "tls"
+	}
+	return fmt.Sprintf("%v://%v", scheme, net.JoinHostPort(nw.Host, nw.Port))
+}
+
+func sendNetwork(options libs.Options, nw libs.Network, req libs.Request) (libs.Response, error) {
+	var res libs.Response
+	timeout := options.Timeout
+	if nw.Timeout > 0 {
+		timeout = nw.Timeout
+	}
+	if timeout <= 0 {
+		timeout = 20
+	}
+
+	address := net.JoinHostPort(nw.Host, nw.Port)
+	payload := DecodeEscapes(nw.Data)
+	if options.Verbose {
+		fmt.Printf("[Sent][Network] %v %v bytes\n", address, len(payload))
+	}
+
+	proxy, pooled, err := RequestProxy(options, req)
+	if err != nil {
+		return res, err
+	}
+
+	timeStart := time.Now()
+	dialer := &net.Dialer{Timeout: time.Duration(timeout) * time.Second}
+	conn, err := DialProxy(dialer, proxy, address)
+	if pooled {
+		proxyPool.Report(proxy, err)
+	}
+	if err != nil {
+		utils.DebugF("%v %v", address, err)
+		return res, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(timeStart.Add(time.Duration(timeout) * time.Second))
+
+	if nw.TLS {
+		tlsCfg, err := GetTLSConfig(options.TLS, isHTTPProxy(proxy))
+		if err != nil {
+			utils.ErrorF("%v", err)
+			return res, err
+		}
+		tlsCfg = tlsCfg.Clone()
+		if tlsCfg.ServerName == "" {
+			tlsCfg.ServerName = nw.Host
+		}
+		tlsConn := tls.Client(conn, tlsCfg)
+		if err = tlsConn.Handshake(); err != nil {
+			utils.DebugF("%v %v", address, err)
+			return res, err
+		}
+		conn = tlsConn
+	}
+	if len(payload) > 0 {
+		if _, err = conn.Write(payload); err != nil {
+			utils.ErrorF("%v %v", address, err)
+			return res, err
+		}
+	}
+
+	// banner or response, keep whatever we got before the timeout or the max size
+	var received bytes.Buffer
+	reader := io.LimitReader(conn, maxRawBodySize)
+	delimiter := DecodeEscapes(nw.Delimiter)
+	buf := make([]byte, 4096)
+	for {
+		n, err := reader.Read(buf)
+		received.Write(buf[:n])
+		if len(delimiter) > 0 && bytes.Contains(received.Bytes(), delimiter) {
+			break
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	res.Body = received.String()
+	res.Beautify = res.Body
+	res.Length = received.Len()
+	res.ResponseTime = time.Since(timeStart).Seconds()
+	return res, nil
+}
@@ -0,0 +1,104 @@
+package sender
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jaeles-project/jaeles/libs"
+)
+
+func TestSendNetwork(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				line, _ := bufio.NewReader(conn).ReadString('\n')
+				if line == "INFO\r\n" {
+					conn.Write([]byte("$20\r\n# Server\r\nredis_version:6.0\r\n"))
+				}
+				// keep the connection open, client should stop at the delimiter
+				buf := make([]byte, 1)
+				conn.Read(buf)
+			}(conn)
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	var options libs.Options
+	options.Timeout = 5
+	res, err := SendNetwork(options, libs.Network{Host: host, Port: port, Data: `INFO\r\n`, Delimiter: `redis_version:6.0\r\n`})
+	if err != nil {
+		t.Fatalf("Error sending: %v", err)
+	}
+	if res.Body != "$20\r\n# Server\r\nredis_version:6.0\r\n" || res.ResponseTime > 4 {
+		t.Errorf("Unexpected response: %q %v", res.Body, res.ResponseTime)
+	}
+}
+
+func TestSendNetworkProxy(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			// banner bigger than we keep
+			conn.Write([]byte(strings.Repeat("a", maxRawBodySize+10)))
+			conn.Close()
+		}
+	}()
+
+	var tunnels int
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		tunnels++
+		w.WriteHeader(http.StatusOK)
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		io.Copy(conn, target)
+		conn.Close()
+	}))
+	defer proxy.Close()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	nw := libs.Network{Host: host, Port: port}
+	options := libs.Options{Timeout: 5, Proxy: proxy.URL, RecordDir: t.TempDir()}
+	res, err := SendNetwork(options, nw)
+	if err != nil {
+		t.Fatalf("Error sending through proxy: %v", err)
+	}
+	if tunnels != 1 || res.Length != maxRawBodySize {
+		t.Errorf("Expect capped banner through the proxy, got %v bytes (%v tunnels)", res.Length, tunnels)
+	}
+
+	// replay without network
+	ln.Close()
+	options.ReplayDir = options.RecordDir
+	options.RecordDir = ""
+	replayed, err := SendNetwork(options, nw)
+	if err != nil || replayed.Length != res.Length || tunnels != 1 {
+		t.Errorf("Expect recorded response, got %v bytes %v", replayed.Length, err)
+	}
+}
@@ -0,0 +1,244 @@
+package sender
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jaeles-project/jaeles/libs"
+	"github.com/jaeles-project/jaeles/utils"
+	xproxy "golang.org/x/net/proxy"
+)
+
+// drop proxy from the pool after this many failures in a row
+const maxProxyFailures = 3
+
+// ProxyPool rotate requests across a list of proxies
+type ProxyPool struct {
+	mu       sync.Mutex
+	proxies  []string
+	failures map[string]int
+	next     int
+	random   bool
+}
+
+var proxyPool *ProxyPool
+
+// InitProxyPool load proxies from file and only keep the alive ones
+func InitProxyPool(options libs.Options) error {
+	var proxies []string
+	for _, line := range utils.ReadingLines(options.ProxyFile) {
+		proxy := NormalizeProxy(line)
+		if proxy == "" {
+			continue
+		}
+		if err := CheckProxy(proxy, options.Timeout); err != nil {
+			utils.WarningF("Drop dead proxy %v: %v", proxy, err)
+			continue
+		}
+		proxies = append(proxies, proxy)
+	}
+	if len(proxies) == 0 {
+		return fmt.Errorf("no alive proxy in %v", options.ProxyFile)
+	}
+	utils.InforF("Loaded %v proxies", len(proxies))
+
+	proxyPool = &ProxyPool{
+		proxies:  proxies,
+		failures: make(map[string]int),
+		random:   options.ProxyRotate == "random",
+	}
+	return nil
+}
+
+// NormalizeProxy add http scheme when proxy only contain host:port
+func NormalizeProxy(proxy string) string {
+	proxy = strings.TrimSpace(proxy)
+	if proxy == "" || strings.HasPrefix(proxy, "#") {
+		return ""
+	}
+	if !strings.Contains(proxy, "://") {
+		proxy = "http://" + proxy
+	}
+	return proxy
+}
+
+// CheckProxy make sure we can connect to the proxy
+func CheckProxy(proxy string, timeout int) error {
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return err
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return fmt.Errorf("unsupported proxy scheme: %v", u.Scheme)
+	}
+	if timeout <= 0 {
+		timeout = 20
+	}
+	conn, err := net.DialTimeout("tcp", u.Host, time.Duration(timeout)*time.Second)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// PickProxy get proxy for the next request
+func PickProxy(options libs.Options) string {
+	if proxyPool == nil {
+		return options.Proxy
+	}
+	return proxyPool.Pick()
+}
+
+// RequestProxy get proxy of the request or pick one from the pool, pooled tell if it should be reported to the pool
+func RequestProxy(options libs.Options, req libs.Request) (proxy string, pooled bool, err error) {
+	// override proxy
+	if req.Proxy != "" && req.Proxy != "blank" {
+		return req.Proxy, false, nil
+	}
+	proxy = PickProxy(options)
+	pooled = proxyPool != nil
+	if pooled && proxy == "" {
+		return "", pooled, errors.New("no proxy left in the pool")
+	}
+	return proxy, pooled, nil
+}
+
+// DialProxy open TCP connection to the address through HTTP CONNECT or SOCKS5 proxy, directly without proxy
+func DialProxy(dialer *net.Dialer, proxy string, address string) (net.Conn, error) {
+	if proxy == "" {
+		return dialer.Dial("tcp", address)
+	}
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy %v: %v", proxy, err)
+	}
+	switch u.Scheme {
+	case "socks5", "socks5h":
+		var auth *xproxy.Auth
+		if u.User != nil {
+			password, _ := u.User.Password()
+			auth = &xproxy.Auth{User: u.User.Username(), Password: password}
+		}
+		socks, err := xproxy.SOCKS5("tcp", u.Host, auth, dialer)
+		if err != nil {
+			return nil, err
+		}
+		return socks.Dial("tcp", address)
+	case "http", "https":
+		return dialConnect(dialer, u, address)
+	}
+	return nil, fmt.Errorf("unsupported proxy scheme: %v", u.Scheme)
+}
+
+// dialConnect open a tunnel to the address with CONNECT method of HTTP proxy
+func dialConnect(dialer *net.Dialer, u *url.URL, address string) (net.Conn, error) {
+	var conn net.Conn
+	var err error
+	if u.Scheme == "https" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", u.Host, &tls.Config{InsecureSkipVerify: true})
+	} else {
+		conn, err = dialer.Dial("tcp", u.Host)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("proxyconnect %v: %v", u.Host, err)
+	}
+	if dialer.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(dialer.Timeout))
+	}
+
+	connect := fmt.Sprintf("CONNECT %v HTTP/1.1\r\nHost: %v\r\n", address, address)
+	if u.User != nil {
+		password, _ := u.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(u.User.Username() + ":" + password))
+		connect += fmt.Sprintf("Proxy-Authorization: Basic %v\r\n", credentials)
+	}
+	connect += "\r\n"
+	if _, err := conn.Write([]byte(connect)); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("proxyconnect %v: %v", u.Host, err)
+	}
+	// nothing come after the response until we write through the tunnel,
+	// the body is never read since it last until the tunnel is closed
+	res, err := http.ReadResponse(bufio.NewReader(conn), &http.Request{Method: "CONNECT"})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("proxyconnect %v: %v", u.Host, err)
+	}
+	if res.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxyconnect %v: %v", u.Host, res.Status)
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// Pick get next proxy in round-robin or random order
+func (p *ProxyPool) Pick() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.proxies) == 0 {
+		return ""
+	}
+	if p.random {
+		return p.proxies[rand.Intn(len(p.proxies))]
+	}
+	proxy := p.proxies[p.next%len(p.proxies)]
+	p.next++
+	return proxy
+}
+
+// Report update the health of proxy after sending a request with it
+func (p *ProxyPool) Report(proxy string, err error) {
+	if proxy == "" {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !isProxyError(err) {
+		p.failures[proxy] = 0
+		return
+	}
+	p.failures[proxy]++
+	if p.failures[proxy] < maxProxyFailures {
+		return
+	}
+
+	for index, item := range p.proxies {
+		if item == proxy {
+			p.proxies = append(p.proxies[:index], p.proxies[index+1:]...)
+			utils.WarningF("Drop failing proxy %v (%v left)", proxy, len(p.proxies))
+			break
+		}
+	}
+}
+
+// isProxyError only blame the proxy when we can't go through it
+func isProxyError(err error) bool {
+	if err == nil {
+		return false
+	}
+	message := err.Error()
+	for _, pattern := range []string{"proxyconnect", "socks connect", "ERR_PROXY_CONNECTION_FAILED", "ERR_TUNNEL_CONNECTION_FAILED", "ERR_SOCKS_CONNECTION_FAILED"} {
+		if strings.Contains(message, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// isHTTPProxy check if proxy is a HTTP proxy like Burp
+func isHTTPProxy(proxy string) bool {
+	return strings.HasPrefix(proxy, "http://") || strings.HasPrefix(proxy, "https://")
+}
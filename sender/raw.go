@@ -0,0 +1,431 @@
+package sender
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jaeles-project/jaeles/libs"
+	"github.com/jaeles-project/jaeles/utils"
+)
+
+// maximum body size read from a raw response, the rest is dropped
+const maxRawBodySize = 10 << 20
+
+// matching request line at the start of a message, used to count pipelined requests
+var requestLineRegex = regexp.MustCompile(`(?m)^[!#$%&'*+\-.^_|~0-9A-Za-z]+ \S+ HTTP/[0-9.]+\r?$`)
+
+// SendRaw write raw request byte by byte over TCP/TLS and parse the response tolerantly
+func SendRaw(options libs.Options, req libs.Request) (libs.Response, error) {
+	started := time.Now()
+	key := ReplayKey(req)
+	var res libs.Response
+	var err error
+	if options.ReplayDir != "" {
+		res, err = ReplayFixture(options, key, req)
+	} else {
+		res, err = sendRaw(options, req)
+	}
+	RecordHAR(options, req, res, err, started)
+	RecordFixture(options, key, req, res, err)
+	return res, err
+}
+
+func sendRaw(options libs.Options, req libs.Request) (libs.Response, error) {
+	var res libs.Response
+	timeout := options.Timeout
+	if req.Timeout > 0 {
+		timeout = req.Timeout
+	}
+	if timeout <= 0 {
+		timeout = 20
+	}
+
+	address, useTLS, serverName, err := rawAddress(req)
+	if err != nil {
+		utils.ErrorF("%v", err)
+		return res, err
+	}
+	payload := PrepareRaw(req.Raw, req.RawCRLF, req.RawEscapes)
+	if options.Verbose {
+		fmt.Printf("[Sent][Raw] %v %v bytes\n", address, len(payload))
+	}
+
+	proxy, pooled, err := RequestProxy(options, req)
+	if err != nil {
+		return res, err
+	}
+
+	timeStart := time.Now()
+	deadline := timeStart.Add(time.Duration(timeout) * time.Second)
+	dialer := &net.Dialer{Timeout: time.Duration(timeout) * time.Second}
+	// the raw bytes go through a tunnel so the proxy never rewrite them
+	conn, err := DialProxy(dialer, proxy, address)
+	if pooled {
+		proxyPool.Report(proxy, err)
+	}
+	if err != nil {
+		utils.ErrorF("%v %v", address, err)
+		return res, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(deadline)
+
+	if useTLS {
+		profile := MergeTLS(options.TLS, req.TLS)
+		tlsCfg, err := GetTLSConfig(profile, isHTTPProxy(proxy))
+		if err != nil {
+			utils.ErrorF("%v", err)
+			return res, err
+		}
+		tlsCfg = tlsCfg.Clone()
+		if tlsCfg.ServerName == "" {
+			tlsCfg.ServerName = serverName
+		}
+		tlsConn := tls.Client(conn, tlsCfg)
+		if err = tlsConn.Handshake(); err != nil {
+			utils.ErrorF("%v %v", address, err)
+			return res, err
+		}
+		conn = tlsConn
+	}
+	if _, err = conn.Write(payload); err != nil {
+		utils.ErrorF("%v %v", address, err)
+		return res, err
+	}
+
+	// read as many responses as requests we wrote, then wait a bit for extra one
+	expected := len(requestLineRegex.FindAll(payload, -1))
+	if expected == 0 {
+		expected = 1
+	}
+	var raw bytes.Buffer
+	reader := bufio.NewReader(io.TeeReader(conn, &raw))
+	var responses []libs.Response
+	for {
+		if len(responses) >= expected {
+			grace := time.Now().Add(500 * time.Millisecond)
+			if grace.Before(deadline) {
+				conn.SetReadDeadline(grace)
+			}
+		}
+		parsed, err := ReadRawResponse(reader, req.Method)
+		// interim response like 100 Continue come before the final one
+		interim := parsed.StatusCode/100 == 1 && parsed.StatusCode != 101
+		if err != nil {
+			if (parsed.StatusCode != 0 || parsed.Body != "") && !interim {
+				responses = append(responses, parsed)
+			}
+			break
+		}
+		if !interim {
+			responses = append(responses, parsed)
+		}
+	}
+	resTime := time.Since(timeStart).Seconds()
+
+	// detections can check each pipelined response
+	for index := range responses {
+		responses[index].Beautify = BeautifyResponse(responses[index])
+		responses[index].Length = len(responses[index].Beautify)
+	}
+	if len(responses) > 0 {
+		res = responses[0]
+		res.Responses = responses
+	}
+	res.Beautify = raw.String()
+	res.Length = raw.Len()
+	res.ResponseTime = resTime
+	if raw.Len() == 0 {
+		return res, fmt.Errorf("no response from %v", address)
+	}
+	if req.EnableChecksum {
+		GenCheckSum(&res)
+	}
+	return res, nil
+}
+
+// rawAddress get address to connect from request URL or Host header in raw request
+func rawAddress(req libs.Request) (address string, useTLS bool, serverName string, err error) {
+	rawURL := req.URL
+	if rawURL == "" {
+		host := rawHeader(req.Raw, "Host")
+		if host == "" {
+			return "", false, "", fmt.Errorf("no target for raw request")
+		}
+		scheme := req.Scheme
+		if scheme == "" {
+			scheme = "https"
+		}
+		rawURL = fmt.Sprintf("%v://%v", scheme, host)
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", false, "", err
+	}
+	useTLS = u.Scheme == "https"
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if useTLS {
+			port = "443"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port), useTLS, u.Hostname(), nil
+}
+
+// rawHeader get header value from raw request
+func rawHeader(raw string, name string) string {
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			break
+		}
+		if index := strings.Index(line, ":"); index > 0 {
+			if strings.EqualFold(strings.TrimSpace(line[:index]), name) {
+				return strings.TrimSpace(line[index+1:])
+			}
+		}
+	}
+	return ""
+}
+
+// PrepareRaw keep raw request as it is unless crlf is enabled, then new line is turned to CRLF in the head
+// of each message and body is sent as it is. A line look like request line start a new message so pipelined requests work.
+// Escapes like \r, \n, \t and \xHH are decoded when enabled, so you can still send bare LF or weird bytes
+func PrepareRaw(raw string, crlf bool, escapes bool) []byte {
+	if !crlf {
+		if escapes {
+			return DecodeEscapes(raw)
+		}
+		return []byte(raw)
+	}
+	var buf strings.Builder
+	inHead := true
+	for len(raw) > 0 {
+		line := raw
+		if end := strings.IndexByte(raw, '\n'); end >= 0 {
+			line = raw[:end+1]
+		}
+		raw = raw[len(line):]
+		content := strings.TrimRight(line, "\r\n")
+		if !inHead && requestLineRegex.MatchString(content) {
+			inHead = true
+		}
+		if !inHead {
+			buf.WriteString(line)
+			continue
+		}
+		buf.WriteString(content)
+		if strings.HasSuffix(line, "\n") {
+			buf.WriteString("\r\n")
+		}
+		if content == "" {
+			inHead = false
+		}
+	}
+	if escapes {
+		return DecodeEscapes(buf.String())
+	}
+	return []byte(buf.String())
+}
+
+// DecodeEscapes decode \r, \n, \t, \0, \\ and \xHH sequences, keep the rest as it is
+func DecodeEscapes(raw string) []byte {
+	var buf bytes.Buffer
+	for i := 0; i < len(raw); i++ {
+		if raw[i] != '\\' || i+1 >= len(raw) {
+			buf.WriteByte(raw[i])
+			continue
+		}
+		switch raw[i+1] {
+		case 'r':
+			buf.WriteByte('\r')
+			i++
+		case 'n':
+			buf.WriteByte('\n')
+			i++
+		case 't':
+			buf.WriteByte('\t')
+			i++
+		case '0':
+			buf.WriteByte(0)
+			i++
+		case '\\':
+			buf.WriteByte('\\')
+			i++
+		case 'x':
+			if i+3 < len(raw) {
+				if b, err := hex.DecodeString(raw[i+2 : i+4]); err == nil {
+					buf.Write(b)
+					i += 3
+					continue
+				}
+			}
+			buf.WriteByte(raw[i])
+		default:
+			buf.WriteByte(raw[i])
+		}
+	}
+	return buf.Bytes()
+}
+
+// ReadRawResponse parse a single response without being strict like net/http
+func ReadRawResponse(reader *bufio.Reader, method string) (libs.Response, error) {
+	var res libs.Response
+	statusLine, err := readRawLine(reader)
+	if err != nil {
+		return res, err
+	}
+	// skip blank line between pipelined responses
+	for statusLine == "" {
+		statusLine, err = readRawLine(reader)
+		if err != nil {
+			return res, err
+		}
+	}
+
+	// not a HTTP response at all, take everything as a body
+	if !strings.HasPrefix(statusLine, "HTTP/") {
+		rest, err := io.ReadAll(io.LimitReader(reader, maxRawBodySize))
+		res.Body = statusLine + "\n" + string(rest)
+		if err == nil {
+			err = io.EOF
+		}
+		return res, err
+	}
+	parts := strings.SplitN(statusLine, " ", 3)
+	if len(parts) > 1 {
+		res.StatusCode, _ = strconv.Atoi(strings.TrimSpace(parts[1]))
+	}
+	proto := parts[0]
+	status := strings.Join(parts[1:], " ")
+	res.Status = fmt.Sprintf("%v %v", status, proto)
+
+	// headers with obs-fold support
+	var contentLength int64 = -1
+	var chunked, invalidLength bool
+	for {
+		line, err := readRawLine(reader)
+		if err != nil {
+			return res, err
+		}
+		if line == "" {
+			break
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(res.Headers) > 0 {
+			for k, v := range res.Headers[len(res.Headers)-1] {
+				res.Headers[len(res.Headers)-1][k] = v + " " + strings.TrimSpace(line)
+			}
+			continue
+		}
+		index := strings.Index(line, ":")
+		if index <= 0 {
+			continue
+		}
+		key := strings.TrimSpace(line[:index])
+		value := strings.TrimSpace(line[index+1:])
+		res.Headers = append(res.Headers, map[string]string{key: value})
+		switch strings.ToLower(key) {
+		case "content-length":
+			// read until the connection is closed or timeout when the length can't be trusted
+			length, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || length < 0 {
+				invalidLength = true
+				continue
+			}
+			contentLength = length
+		case "transfer-encoding":
+			chunked = strings.Contains(strings.ToLower(value), "chunked")
+		}
+	}
+
+	// some response never have body
+	if res.StatusCode/100 == 1 || res.StatusCode == 204 || res.StatusCode == 304 || strings.EqualFold(method, "HEAD") {
+		return res, nil
+	}
+
+	var body []byte
+	switch {
+	case chunked:
+		body, err = readRawChunked(reader)
+	case contentLength >= 0 && !invalidLength:
+		body, err = io.ReadAll(io.LimitReader(reader, minInt64(contentLength, maxRawBodySize)))
+		if err == nil && int64(len(body)) < contentLength {
+			err = io.ErrUnexpectedEOF
+			if len(body) == maxRawBodySize {
+				err = fmt.Errorf("body larger than %v bytes", maxRawBodySize)
+			}
+		}
+	default:
+		body, err = io.ReadAll(io.LimitReader(reader, maxRawBodySize))
+		if err == nil {
+			err = io.EOF
+		}
+	}
+	res.Body = string(body)
+	return res, err
+}
+
+func readRawLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func readRawChunked(reader *bufio.Reader) ([]byte, error) {
+	var body []byte
+	for {
+		line, err := readRawLine(reader)
+		if err != nil {
+			return body, err
+		}
+		sizeString := strings.TrimSpace(strings.SplitN(line, ";", 2)[0])
+		size, err := strconv.ParseInt(sizeString, 16, 64)
+		if err != nil {
+			return body, err
+		}
+		if size < 0 {
+			return body, fmt.Errorf("invalid chunk size: %v", sizeString)
+		}
+		if int64(len(body))+size > maxRawBodySize {
+			return body, fmt.Errorf("body larger than %v bytes", maxRawBodySize)
+		}
+		if size == 0 {
+			// trailers until blank line
+			for {
+				line, err := readRawLine(reader)
+				if err != nil || line == "" {
+					return body, nil
+				}
+			}
+		}
+		chunk, err := io.ReadAll(io.LimitReader(reader, size))
+		body = append(body, chunk...)
+		if err == nil && int64(len(chunk)) < size {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return body, err
+		}
+		readRawLine(reader)
+	}
+}
+
+func minInt64(a int64, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,208 @@
+package sender
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/jaeles-project/jaeles/libs"
+)
+
+func TestPrepareRaw(t *testing.T) {
+	raw := "GET / HTTP/1.1\nHost: example.com\nX-Fold: a\\n b\\x00\n\n"
+	got := PrepareRaw(raw, true, true)
+	want := []byte("GET / HTTP/1.1\r\nHost: example.com\r\nX-Fold: a\n b\x00\r\n\r\n")
+	if !bytes.Equal(got, want) {
+		t.Errorf("Error preparing raw request: %q", got)
+	}
+
+	// sent byte for byte unless CRLF is enabled
+	if got := PrepareRaw(raw, false, false); string(got) != raw {
+		t.Errorf("Expect raw request unchanged, got %q", got)
+	}
+	if got := PrepareRaw(raw, false, true); string(got) != "GET / HTTP/1.1\nHost: example.com\nX-Fold: a\n b\x00\n\n" {
+		t.Errorf("Expect only escapes decoded, got %q", got)
+	}
+
+	// body is kept as it is, escapes are only decoded when enabled
+	body := "{\"a\": \"x\\ny\\\\z\", \"b\": \"\\x3cscript\"}\nline"
+	raw = "POST / HTTP/1.1\nHost: example.com\nX-A: \\n\n\n" + body + "\nGET /b HTTP/1.1\nHost: example.com\n\n"
+	got = PrepareRaw(raw, true, false)
+	want = []byte("POST / HTTP/1.1\r\nHost: example.com\r\nX-A: \\n\r\n\r\n" + body + "\nGET /b HTTP/1.1\r\nHost: example.com\r\n\r\n")
+	if !bytes.Equal(got, want) {
+		t.Errorf("Expect body unchanged, got %q", got)
+	}
+}
+
+func TestSendRawBody(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	body := "{\"a\": \"x\\ny\\\\z\", \"b\": \"\\x3cscript\"}\n"
+	received := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		var data []byte
+		for !strings.HasSuffix(string(data), "\r\n\r\n") {
+			b, err := reader.ReadByte()
+			if err != nil {
+				break
+			}
+			data = append(data, b)
+		}
+		sent := make([]byte, len(body))
+		io.ReadFull(reader, sent)
+		received <- string(data) + string(sent)
+		conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok"))
+	}()
+
+	req := libs.Request{
+		URL:     "http://" + ln.Addr().String(),
+		Raw:     fmt.Sprintf("POST / HTTP/1.1\nHost: x\nContent-Length: %v\n\n%v", len(body), body),
+		RawCRLF: true,
+	}
+	if _, err := SendRaw(libs.Options{Timeout: 3}, req); err != nil {
+		t.Fatalf("Error sending raw request: %v", err)
+	}
+	data := <-received
+	if parts := strings.SplitN(data, "\r\n\r\n", 2); len(parts) != 2 || parts[1] != body {
+		t.Errorf("Expect body bytes unchanged, got %q", data)
+	}
+}
+
+func TestReadRawResponseSize(t *testing.T) {
+	responses := []string{
+		"HTTP/1.1 200 OK\r\nContent-Length: 99999999999999999\r\n\r\nsmall",
+		"HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n-1\r\nsmall\r\n0\r\n\r\n",
+		"HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n7fffffffffffffff\r\nsmall\r\n0\r\n\r\n",
+	}
+	for _, raw := range responses {
+		if _, err := ReadRawResponse(bufio.NewReader(strings.NewReader(raw)), "GET"); err == nil {
+			t.Errorf("Expect error on size of %q", raw)
+		}
+	}
+	// invalid length is ignored and body is read until the end
+	for _, length := range []string{"-1", "abc"} {
+		raw := "HTTP/1.1 200 OK\r\nContent-Length: " + length + "\r\n\r\nsmall"
+		res, _ := ReadRawResponse(bufio.NewReader(strings.NewReader(raw)), "GET")
+		if res.StatusCode != 200 || res.Body != "small" {
+			t.Errorf("Expect body read until EOF with Content-Length %v, got %v %q", length, res.StatusCode, res.Body)
+		}
+	}
+	// non HTTP response is cut at the max size too
+	garbage := "SSH-2.0-OpenSSH\r\n" + strings.Repeat("a", maxRawBodySize+10)
+	res, err := ReadRawResponse(bufio.NewReader(strings.NewReader(garbage)), "GET")
+	if err == nil || len(res.Body) > maxRawBodySize+len("SSH-2.0-OpenSSH\n") {
+		t.Errorf("Expect non HTTP body cut at the max size, got %v %v", len(res.Body), err)
+	}
+	big := "HTTP/1.1 200 OK\r\nContent-Length: 99999999999\r\n\r\n" + strings.Repeat("a", maxRawBodySize+10)
+	res, err = ReadRawResponse(bufio.NewReader(strings.NewReader(big)), "GET")
+	if err == nil || len(res.Body) != maxRawBodySize {
+		t.Errorf("Expect body cut at the max size, got %v %v", len(res.Body), err)
+	}
+}
+
+func TestSendRawPipeline(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		var data []byte
+		for strings.Count(string(data), "\r\n\r\n") < 2 {
+			b, err := reader.ReadByte()
+			if err != nil {
+				break
+			}
+			data = append(data, b)
+		}
+		received <- string(data)
+		conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Length: 5\r\nX-Fold: a\r\n  b\r\n\r\nfirst" +
+			"HTTP/1.1 404 Not Found\r\nTransfer-Encoding: chunked\r\n\r\n3\r\nsec\r\n3\r\nond\r\n0\r\n\r\n"))
+	}()
+
+	req := libs.Request{
+		URL:     "http://" + ln.Addr().String(),
+		Raw:     "GET /a HTTP/1.1\nHost: x\nContent-Length: 0\nContent-Length: 1\n\nGET /b HTTP/1.1\nHost: x\n\n",
+		RawCRLF: true,
+	}
+	res, err := SendRaw(libs.Options{Timeout: 3}, req)
+	if err != nil {
+		t.Fatalf("Error sending raw request: %v", err)
+	}
+	if data := <-received; !strings.Contains(data, "Content-Length: 0\r\nContent-Length: 1\r\n") {
+		t.Errorf("Raw request was not sent as it is: %q", data)
+	}
+	if res.StatusCode != 200 || res.Body != "first" {
+		t.Errorf("Error parsing first response: %v %q", res.StatusCode, res.Body)
+	}
+	if res.Headers[1]["X-Fold"] != "a b" {
+		t.Errorf("Error parsing folded header: %v", res.Headers)
+	}
+	if !strings.Contains(res.Beautify, "404 Not Found") {
+		t.Errorf("Pipelined response missing: %q", res.Beautify)
+	}
+	if len(res.Responses) != 2 || res.Responses[1].StatusCode != 404 || res.Responses[1].Body != "second" {
+		t.Errorf("Expect every pipelined response, got %v", res.Responses)
+	}
+}
+
+func TestSendRawInterim(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		var data []byte
+		for !strings.Contains(string(data), "\r\n\r\n") {
+			b, err := reader.ReadByte()
+			if err != nil {
+				return
+			}
+			data = append(data, b)
+		}
+		conn.Write([]byte("HTTP/1.1 100 Continue\r\n\r\nHTTP/1.1 103 Early Hints\r\nLink: </a.css>\r\n\r\n" +
+			"HTTP/1.1 201 Created\r\nContent-Length: 4\r\n\r\ndone"))
+	}()
+
+	req := libs.Request{
+		URL: "http://" + ln.Addr().String(),
+		Raw: "POST / HTTP/1.1\r\nHost: x\r\nExpect: 100-continue\r\nContent-Length: 0\r\n\r\n",
+	}
+	res, err := SendRaw(libs.Options{Timeout: 3}, req)
+	if err != nil {
+		t.Fatalf("Error sending raw request: %v", err)
+	}
+	if res.StatusCode != 201 || res.Body != "done" {
+		t.Errorf("Expect final response after interim ones, got %v %q", res.StatusCode, res.Body)
+	}
+}
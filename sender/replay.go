@@ -0,0 +1,170 @@
+package sender
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/jaeles-project/jaeles/libs"
+	"github.com/jaeles-project/jaeles/utils"
+)
+
+// headers that change between runs so don't use them to match request
+var volatileHeaders = map[string]bool{
+	"user-agent":     true,
+	"content-length": true,
+	"connection":     true,
+	"authorization":  true,
+	"cookie":         true,
+}
+
+// fixture recorded request and response
+type fixture struct {
+	Key      string
+	Request  string
+	Response libs.Response
+}
+
+var (
+	replayMu     sync.Mutex
+	replayMisses []string
+)
+
+// placeholder of the OOB ID in replay key, the ID is random on every run
+const replayOOBPlaceholder = "[[oob]]"
+
+// ReplayKey hash the normalized request so the same request always give the same key
+func ReplayKey(req libs.Request) string {
+	req = withoutOOB(req)
+	method := strings.ToUpper(strings.TrimSpace(req.Method))
+	if method == "" {
+		method = "GET"
+	}
+	if req.Engine == "raw" {
+		return hashKey("raw", req.URL, req.Raw)
+	}
+	if req.Engine == "network" {
+		return hashKey("network", req.URL, req.Body)
+	}
+
+	var headers []string
+	for _, header := range req.Headers {
+		for k, v := range header {
+			if volatileHeaders[strings.ToLower(k)] {
+				continue
+			}
+			headers = append(headers, fmt.Sprintf("%v: %v", strings.ToLower(k), strings.TrimSpace(v)))
+		}
+	}
+	sort.Strings(headers)
+	if req.Engine == "websocket" {
+		return hashKey("websocket", normalizeURL(req.URL), strings.Join(headers, "\n"), req.Body, strings.Join(req.Messages, "\x00"))
+	}
+	return hashKey(req.Engine, method, normalizeURL(req.URL), strings.Join(headers, "\n"), req.Body)
+}
+
+// withoutOOB replace OOB ID of the request with a fixed placeholder
+func withoutOOB(req libs.Request) libs.Request {
+	if req.OOB == "" {
+		return req
+	}
+	replace := func(value string) string {
+		return strings.ReplaceAll(value, req.OOB, replayOOBPlaceholder)
+	}
+	req.URL = replace(req.URL)
+	req.Body = replace(req.Body)
+	req.Raw = replace(req.Raw)
+	var messages []string
+	for _, message := range req.Messages {
+		messages = append(messages, replace(message))
+	}
+	req.Messages = messages
+	var headers []map[string]string
+	for _, header := range req.Headers {
+		element := make(map[string]string)
+		for k, v := range header {
+			element[k] = replace(v)
+		}
+		headers = append(headers, element)
+	}
+	req.Headers = headers
+	return req
+}
+
+// normalizeURL sort query params so order doesn't matter
+func normalizeURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	if u.RawQuery != "" {
+		u.RawQuery = u.Query().Encode()
+	}
+	return u.String()
+}
+
+func hashKey(parts ...string) string {
+	return fmt.Sprintf("%x", sha1.Sum([]byte(strings.Join(parts, "\x00"))))
+}
+
+// RecordFixture store response of the request to record folder
+func RecordFixture(options libs.Options, key string, req libs.Request, res libs.Response, err error) {
+	if options.RecordDir == "" || err != nil {
+		return
+	}
+	data, jerr := json.MarshalIndent(fixture{
+		Key:      key,
+		Request:  fmt.Sprintf("%v %v", req.Method, req.URL),
+		Response: res,
+	}, "", "  ")
+	if jerr != nil {
+		utils.ErrorF("Error encoding fixture: %v", jerr)
+		return
+	}
+	recordDir := utils.NormalizePath(options.RecordDir)
+	utils.MakeDir(recordDir)
+	utils.WriteToFile(path.Join(recordDir, key+".json"), string(data))
+}
+
+// ReplayFixture get recorded response of the request instead of sending it
+func ReplayFixture(options libs.Options, key string, req libs.Request) (libs.Response, error) {
+	var item fixture
+	data, err := ioutil.ReadFile(path.Join(utils.NormalizePath(options.ReplayDir), key+".json"))
+	if err == nil {
+		err = json.Unmarshal(data, &item)
+	}
+	if err != nil {
+		replayMu.Lock()
+		replayMisses = append(replayMisses, fmt.Sprintf("%v %v", req.Method, req.URL))
+		replayMu.Unlock()
+		utils.DebugF("[Replay] no recorded response: %v %v", req.Method, req.URL)
+		return libs.Response{}, fmt.Errorf("no recorded response for %v %v", req.Method, req.URL)
+	}
+	if options.Verbose {
+		fmt.Printf("[Replay] %v %v %v\n", req.Method, req.URL, item.Response.Status)
+	}
+	return item.Response, nil
+}
+
+// ReplayReport print requests that have no recorded response
+func ReplayReport(options libs.Options) {
+	if options.ReplayDir == "" {
+		return
+	}
+	replayMu.Lock()
+	defer replayMu.Unlock()
+	if len(replayMisses) == 0 {
+		utils.InforF("All requests matched recorded responses")
+		return
+	}
+	utils.WarningF("%v requests have no recorded response", len(replayMisses))
+	for _, miss := range replayMisses {
+		utils.WarningF("[Unmatched] %v", miss)
+	}
+}
@@ -0,0 +1,74 @@
+package sender
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jaeles-project/jaeles/libs"
+)
+
+func TestRecordReplay(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("recorded " + r.URL.RawQuery))
+	}))
+	var options libs.Options
+	options.Timeout = 5
+	options.RecordDir = t.TempDir()
+
+	req := libs.Request{Method: "GET", URL: ts.URL + "/?a=1&b=2", Headers: []map[string]string{{"X-Test": "1"}}}
+	if _, err := JustSend(options, req); err != nil {
+		t.Fatalf("Error recording: %v", err)
+	}
+	ts.Close()
+
+	// replay without network, param order and user agent don't matter
+	options.ReplayDir = options.RecordDir
+	options.RecordDir = ""
+	replayReq := libs.Request{Method: "get", URL: ts.URL + "/?b=2&a=1", Headers: []map[string]string{{"X-Test": "1", "User-Agent": "other"}}}
+	res, err := JustSend(options, replayReq)
+	if err != nil || res.Body != "recorded a=1&b=2" {
+		t.Errorf("Expect recorded response, got %v %v", res.Body, err)
+	}
+
+	replayReq.Body = "changed"
+	if _, err := JustSend(options, replayReq); err == nil {
+		t.Errorf("Expect unmatched request to fail")
+	}
+	if len(replayMisses) != 1 {
+		t.Errorf("Expect unmatched request to be reported, got %v", replayMisses)
+	}
+}
+
+func TestReplayKeyOOB(t *testing.T) {
+	// same request of two runs only differ by the random OOB ID
+	first := libs.Request{
+		Method:  "POST",
+		URL:     "http://example.com/?url=http://jx0011223344556677.oob.example.com",
+		Body:    "callback=jx0011223344556677.oob.example.com",
+		Headers: []map[string]string{{"X-Forwarded-Host": "jx0011223344556677.oob.example.com"}},
+		OOB:     "jx0011223344556677",
+	}
+	second := libs.Request{
+		Method:  "POST",
+		URL:     "http://example.com/?url=http://jx8899aabbccddeeff.oob.example.com",
+		Body:    "callback=jx8899aabbccddeeff.oob.example.com",
+		Headers: []map[string]string{{"X-Forwarded-Host": "jx8899aabbccddeeff.oob.example.com"}},
+		OOB:     "jx8899aabbccddeeff",
+	}
+	if ReplayKey(first) != ReplayKey(second) {
+		t.Errorf("Expect requests only differ by OOB ID to have the same key")
+	}
+	first.Engine, second.Engine = "raw", "raw"
+	first.Raw = "GET /?url=" + first.OOB + " HTTP/1.1\r\n\r\n"
+	second.Raw = "GET /?url=" + second.OOB + " HTTP/1.1\r\n\r\n"
+	if ReplayKey(first) != ReplayKey(second) {
+		t.Errorf("Expect raw requests only differ by OOB ID to have the same key")
+	}
+
+	second.Body = "callback=other"
+	second.Engine, first.Engine = "", ""
+	if ReplayKey(first) == ReplayKey(second) {
+		t.Errorf("Expect different requests to have different keys")
+	}
+}
@@ -0,0 +1,153 @@
+package sender
+
+import (
+	"sync"
+
+	"github.com/jaeles-project/jaeles/libs"
+)
+
+// maximum jobs waiting in the scheduler, Submit block after that like the pool used to
+const maxQueuedJobs = 1000
+
+// HostScheduler reserve a slot of the host and IP for every job before handing it to the pool,
+// jobs of busy host wait in the queue instead of blocking a worker of the pool
+type HostScheduler struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	options libs.Options
+	invoke  func(interface{}) error
+	// called when the pool can't take the job
+	failed func(interface{}, error)
+	// FIFO queue of each host, hosts take turns so a busy one doesn't hold the others
+	queues map[string][]scheduledJob
+	// hosts with queued jobs that may get a slot, the others wait for a slot to be released
+	ready   []string
+	blocked map[string]bool
+	queued  int
+	limit   int
+	closed  bool
+	done    chan struct{}
+	stopped func()
+}
+
+type scheduledJob struct {
+	url string
+	job interface{}
+}
+
+// Scheduled job handed to the pool with the slot reserved for it, worker release the slot once the job is done
+type Scheduled struct {
+	Job interface{}
+	// nil when the host isn't limited
+	Slot *HostSlot
+}
+
+// NewHostScheduler create scheduler that invoke Scheduled jobs with the pool, failed is called with jobs the pool refused
+func NewHostScheduler(options libs.Options, invoke func(interface{}) error, failed func(interface{}, error)) *HostScheduler {
+	s := &HostScheduler{
+		options: options,
+		invoke:  invoke,
+		failed:  failed,
+		queues:  make(map[string][]scheduledJob),
+		blocked: make(map[string]bool),
+		limit:   maxQueuedJobs,
+		done:    make(chan struct{}),
+	}
+	s.cond = sync.NewCond(&s.mu)
+	// released slot may let a queued job of blocked hosts go
+	s.stopped = OnRelease(func() {
+		s.mu.Lock()
+		for host := range s.blocked {
+			s.ready = append(s.ready, host)
+		}
+		s.blocked = make(map[string]bool)
+		s.mu.Unlock()
+		s.cond.Broadcast()
+	})
+	go s.dispatch()
+	return s
+}
+
+// Submit queue the job of the URL, block while too many jobs are waiting
+func (s *HostScheduler) Submit(rawURL string, job interface{}) {
+	host := limiterHost(rawURL)
+	if s.options.MaxPerIP > 0 {
+		// resolve outside of the lock
+		resolveHost(host)
+	}
+	s.mu.Lock()
+	for s.queued >= s.limit && !s.closed {
+		s.cond.Wait()
+	}
+	if len(s.queues[host]) == 0 && !s.blocked[host] {
+		s.ready = append(s.ready, host)
+	}
+	s.queues[host] = append(s.queues[host], scheduledJob{url: rawURL, job: job})
+	s.queued++
+	s.mu.Unlock()
+	s.cond.Broadcast()
+}
+
+// Close stop dispatching after every queued job was invoked
+func (s *HostScheduler) Close() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	s.cond.Broadcast()
+	<-s.done
+	s.stopped()
+}
+
+func (s *HostScheduler) dispatch() {
+	defer close(s.done)
+	for {
+		s.mu.Lock()
+		scheduled, ok := s.next()
+		for !ok && !(s.closed && s.queued == 0) {
+			s.cond.Wait()
+			scheduled, ok = s.next()
+		}
+		s.mu.Unlock()
+		if !ok {
+			return
+		}
+		// let blocked Submit go on
+		s.cond.Broadcast()
+		// pool may block here until a worker is free, that's fine as it's not a worker
+		if err := s.invoke(scheduled); err != nil {
+			scheduled.Slot.Release()
+			if s.failed != nil {
+				s.failed(scheduled.Job, err)
+			}
+		}
+	}
+}
+
+// next take the first job of the next ready host that a slot of its host and IP can be reserved for,
+// host without free slot is blocked until a slot is released so it's not checked again in the meantime
+func (s *HostScheduler) next() (Scheduled, bool) {
+	for len(s.ready) > 0 {
+		host := s.ready[0]
+		s.ready = s.ready[1:]
+		jobs := s.queues[host]
+		if len(jobs) == 0 {
+			continue
+		}
+		slot, ok := ReserveHost(s.options, jobs[0].url)
+		if !ok {
+			s.blocked[host] = true
+			continue
+		}
+		queued := jobs[0]
+		jobs[0] = scheduledJob{}
+		if len(jobs) == 1 {
+			delete(s.queues, host)
+		} else {
+			s.queues[host] = jobs[1:]
+			s.ready = append(s.ready, host)
+		}
+		s.queued--
+		return Scheduled{Job: queued.job, Slot: slot}, true
+	}
+	return Scheduled{}, false
+}
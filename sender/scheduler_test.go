@@ -0,0 +1,123 @@
+package sender
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jaeles-project/jaeles/libs"
+	"github.com/panjf2000/ants"
+)
+
+func TestHostScheduler(t *testing.T) {
+	var options libs.Options
+	options.MaxPerHost = 1
+	busy := "http://busy.example.com/"
+	other := "http://other.example.com/"
+
+	var wg sync.WaitGroup
+	var inFlight, maxInFlight int32
+	var otherDone time.Duration
+	start := time.Now()
+
+	// more workers than the busy host can use, queued jobs of it mustn't hold them
+	p, _ := ants.NewPoolWithFunc(3, func(i interface{}) {
+		scheduled := i.(Scheduled)
+		url := scheduled.Job.(string)
+		jobOptions := options
+		if scheduled.Slot != nil {
+			jobOptions.Slot = scheduled.Slot
+		}
+		// job prepare its requests before sending the first one
+		time.Sleep(10 * time.Millisecond)
+		release := AcquireHost(jobOptions, url)
+		if url == busy {
+			current := atomic.AddInt32(&inFlight, 1)
+			if current > atomic.LoadInt32(&maxInFlight) {
+				atomic.StoreInt32(&maxInFlight, current)
+			}
+			time.Sleep(100 * time.Millisecond)
+			atomic.AddInt32(&inFlight, -1)
+		} else {
+			otherDone = time.Since(start)
+		}
+		release()
+		scheduled.Slot.Release()
+		wg.Done()
+	})
+	defer p.Release()
+	scheduler := NewHostScheduler(options, p.Invoke, nil)
+
+	for _, url := range []string{busy, busy, busy, busy, other} {
+		wg.Add(1)
+		scheduler.Submit(url, url)
+	}
+	wg.Wait()
+	scheduler.Close()
+
+	if maxInFlight != 1 {
+		t.Errorf("Expect one job of the busy host at a time, got %v", maxInFlight)
+	}
+	// other host doesn't wait behind jobs of the busy host
+	if otherDone > 50*time.Millisecond {
+		t.Errorf("Expect other host to run right away, took %v", otherDone)
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("Expect jobs of the busy host to run one by one, took %v", elapsed)
+	}
+}
+
+func TestHostSchedulerFailed(t *testing.T) {
+	var options libs.Options
+	options.MaxPerHost = 1
+	url := "http://refused.example.com/"
+
+	var failed int32
+	invoke := func(interface{}) error {
+		return ants.ErrPoolClosed
+	}
+	scheduler := NewHostScheduler(options, invoke, func(job interface{}, err error) {
+		atomic.AddInt32(&failed, 1)
+	})
+	scheduler.Submit(url, url)
+	scheduler.Submit(url, url)
+	scheduler.Close()
+
+	// slot of the refused job is given back so the next one can go
+	if failed != 2 {
+		t.Errorf("Expect both refused jobs to be reported, got %v", failed)
+	}
+}
+
+func TestHostSchedulerBounded(t *testing.T) {
+	var options libs.Options
+	release := make(chan struct{})
+	scheduler := NewHostScheduler(options, func(interface{}) error {
+		<-release
+		return nil
+	}, nil)
+	scheduler.mu.Lock()
+	scheduler.limit = 2
+	scheduler.mu.Unlock()
+
+	var submitted int32
+	go func() {
+		for i := 0; i < 10; i++ {
+			scheduler.Submit(fmt.Sprintf("http://host%v.example.com/", i), i)
+			atomic.AddInt32(&submitted, 1)
+		}
+	}()
+	time.Sleep(50 * time.Millisecond)
+	// one job held by the pool and two in the queue
+	if count := atomic.LoadInt32(&submitted); count != 3 {
+		t.Errorf("Expect Submit to block once the queue is full, got %v submitted", count)
+	}
+
+	close(release)
+	for atomic.LoadInt32(&submitted) != 10 {
+		time.Sleep(time.Millisecond)
+	}
+	scheduler.Close()
+}
@@ -0,0 +1,16 @@
+package sender
+
+import (
+	"strings"
+
+	"github.com/jaeles-project/jaeles/libs"
+)
+
+// IsHTML check if response look like a page worth to take screenshot
+func IsHTML(res libs.Response) bool {
+	if strings.Contains(strings.ToLower(GetHeader(res, "Content-Type")), "html") {
+		return true
+	}
+	body := strings.ToLower(res.Body)
+	return strings.Contains(body, "<html") || strings.Contains(body, "<body")
+}
@@ -1,7 +1,6 @@
 package sender
 
 import (
-	"crypto/tls"
 	"errors"
 	"fmt"
 	"github.com/jaeles-project/jaeles/utils"
@@ -19,6 +18,44 @@ import (
 
 // JustSend just sending request
 func JustSend(options libs.Options, req libs.Request) (res libs.Response, err error) {
+	if authSession != nil && authSession.InScope(req.URL) {
+		return sendWithAuth(options, req, doSend)
+	}
+	return doSend(options, req)
+}
+
+// SendWithEngine send request with the engine it select, auth headers are applied to every engine
+func SendWithEngine(options libs.Options, req libs.Request) (libs.Response, error) {
+	if authSession != nil && authSession.InScope(req.URL) {
+		return sendWithAuth(options, req, sendEngine)
+	}
+	return sendEngine(options, req)
+}
+
+func sendEngine(options libs.Options, req libs.Request) (libs.Response, error) {
+	switch req.Engine {
+	// sending with real browser
+	case "chrome":
+		return SendWithChrome(options, req)
+	// write the raw request as it is
+	case "raw":
+		return SendRaw(options, req)
+	case "websocket":
+		return SendWebSocket(options, req)
+	}
+	return doSend(options, req)
+}
+
+func doSend(options libs.Options, req libs.Request) (res libs.Response, err error) {
+	started := time.Now()
+	fixtureKey := ReplayKey(req)
+	defer func() {
+		RecordHAR(options, req, res, err, started)
+		RecordFixture(options, fixtureKey, req, res, err)
+	}()
+	if options.ReplayDir != "" {
+		return ReplayFixture(options, fixtureKey, req)
+	}
 	if req.Method == "" {
 		req.Method = "GET"
 	}
@@ -26,11 +63,9 @@ func JustSend(options libs.Options, req libs.Request) (res libs.Response, err er
 	url := req.URL
 	body := req.Body
 	headers := GetHeaders(req)
-	proxy := options.Proxy
-
-	// override proxy
-	if req.Proxy != "" && req.Proxy != "blank" {
-		proxy = req.Proxy
+	proxy, pooled, err := RequestProxy(options, req)
+	if err != nil {
+		return res, err
 	}
 
 	timeout := options.Timeout
@@ -60,43 +95,37 @@ func JustSend(options libs.Options, req libs.Request) (res libs.Response, err er
 
 	client := resty.New()
 	client.SetLogger(logger)
-	tlsCfg := &tls.Config{
-		Renegotiation:            tls.RenegotiateOnceAsClient,
-		PreferServerCipherSuites: true,
-		InsecureSkipVerify:       true,
+	profile := MergeTLS(options.TLS, req.TLS)
+	tlsCfg, err := GetTLSConfig(profile, isHTTPProxy(proxy))
+	if err != nil {
+		utils.ErrorF("%v", err)
+		return res, err
 	}
 
-	if proxy != "" {
-		// some times burp reject default cipher
-		tlsCfg = &tls.Config{
-			CipherSuites: []uint16{
-				tls.TLS_RSA_WITH_RC4_128_SHA,
-				tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
-				tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
-			},
-			Renegotiation:            tls.RenegotiateOnceAsClient,
-			PreferServerCipherSuites: true,
-			InsecureSkipVerify:       true,
-		}
+	// reuse connection per host unless the request want a fresh one
+	key := transportKey{
+		Proxy:           proxy,
+		Timeout:         timeout,
+		DisableCompress: disableCompress,
+		TLS:             tlsFingerprint(profile, isHTTPProxy(proxy)),
 	}
-
-	client.SetTransport(&http.Transport{
-		MaxIdleConns:          100,
-		MaxConnsPerHost:       1000,
-		IdleConnTimeout:       time.Duration(timeout) * time.Second,
-		ExpectContinueTimeout: time.Duration(timeout) * time.Second,
-		ResponseHeaderTimeout: time.Duration(timeout) * time.Second,
-		TLSHandshakeTimeout:   time.Duration(timeout) * time.Second,
-		DisableCompression:    disableCompress,
-		DisableKeepAlives:     true,
-		TLSClientConfig:       tlsCfg,
-	})
-
-	if proxy != "" {
-		client.SetProxy(proxy)
+	var transport *http.Transport
+	if req.FreshConn {
+		transport, err = FreshTransport(key, tlsCfg)
+		client.SetCloseConnection(true)
+	} else {
+		transport, err = GetTransport(key, tlsCfg)
 	}
+	if err != nil {
+		utils.ErrorF("%v", err)
+		return res, err
+	}
+	client.SetTransport(transport)
 	client.SetHeaders(headers)
-	client.SetCloseConnection(true)
+	// keep cookies across requests of the session
+	if req.Jar != nil {
+		client.SetCookieJar(req.Jar)
+	}
 
 	if options.Retry > 0 {
 		client.SetRetryCount(options.Retry)
@@ -164,44 +193,12 @@ func JustSend(options libs.Options, req libs.Request) (res libs.Response, err er
 	}
 
 	var resp *resty.Response
-	// really sending things here
-	method = strings.ToLower(strings.TrimSpace(method))
-	switch method {
-	case "get":
-		resp, err = client.R().
-			SetBody([]byte(body)).
-			Get(url)
-		break
-	case "post":
-		resp, err = client.R().EnableTrace().
-			SetBody([]byte(body)).
-			Post(url)
-		break
-	case "head":
-		resp, err = client.R().
-			SetBody([]byte(body)).
-			Head(url)
-		break
-	case "options":
-		resp, err = client.R().
-			SetBody([]byte(body)).
-			Options(url)
-		break
-	case "patch":
-		resp, err = client.R().
-			SetBody([]byte(body)).
-			Patch(url)
-		break
-	case "put":
-		resp, err = client.R().
-			SetBody([]byte(body)).
-			Put(url)
-		break
-	case "delete":
-		resp, err = client.R().
-			SetBody([]byte(body)).
-			Delete(url)
-		break
+	// really sending things here, any method token is sent as it is
+	resp, err = client.R().
+		SetBody([]byte(body)).
+		Execute(NormalizeMethod(method), url)
+	if pooled {
+		proxyPool.Report(proxy, err)
 	}
 
 	// in case we want to get redirect stuff
@@ -227,6 +224,16 @@ func JustSend(options libs.Options, req libs.Request) (res libs.Response, err er
 	return res, nil
 }
 
+// NormalizeMethod upper case standard methods but keep custom verb untouched
+func NormalizeMethod(method string) string {
+	method = strings.TrimSpace(method)
+	switch strings.ToUpper(method) {
+	case "GET", "POST", "HEAD", "OPTIONS", "PATCH", "PUT", "DELETE", "TRACE", "CONNECT":
+		return strings.ToUpper(method)
+	}
+	return method
+}
+
 // ParseResponse field to Response
 func ParseResponse(resp resty.Response) (res libs.Response) {
 	// var res libs.Response
@@ -0,0 +1,33 @@
+package sender
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jaeles-project/jaeles/libs"
+)
+
+func TestCustomMethod(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Method))
+	}))
+	defer ts.Close()
+
+	opt := libs.Options{Timeout: 5}
+	methods := map[string]string{
+		"PROPFIND": "PROPFIND",
+		"TRACE":    "TRACE",
+		"JAELES":   "JAELES",
+		"post":     "POST",
+	}
+	for method, expected := range methods {
+		res, err := JustSend(opt, libs.Request{Method: method, URL: ts.URL})
+		if err != nil {
+			t.Fatalf("Error sending %v request: %v", method, err)
+		}
+		if res.Body != expected {
+			t.Errorf("Expected method %v but got %v", expected, res.Body)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package sender
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"github.com/jaeles-project/jaeles/libs"
+)
+
+// some times burp reject default cipher
+var proxyCiphers = []uint16{
+	tls.TLS_RSA_WITH_RC4_128_SHA,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+var (
+	tlsMu      sync.Mutex
+	tlsConfigs = make(map[string]*tls.Config)
+)
+
+// MergeTLS override global TLS profile with the one from request
+func MergeTLS(global libs.TLSConfig, custom libs.TLSConfig) libs.TLSConfig {
+	merged := global
+	if custom.Cert != "" {
+		merged.Cert = custom.Cert
+		merged.Key = custom.Key
+	}
+	if custom.CA != "" {
+		merged.CA = custom.CA
+	}
+	if custom.MinVersion != "" {
+		merged.MinVersion = custom.MinVersion
+	}
+	if custom.MaxVersion != "" {
+		merged.MaxVersion = custom.MaxVersion
+	}
+	if len(custom.Ciphers) > 0 {
+		merged.Ciphers = custom.Ciphers
+	}
+	if custom.SNI != "" {
+		merged.SNI = custom.SNI
+	}
+	return merged
+}
+
+// tlsFingerprint identify a TLS profile for transport pool
+func tlsFingerprint(profile libs.TLSConfig, proxy bool) string {
+	return fmt.Sprintf("%v|%v|%v|%v|%v|%v|%v|%v", profile.Cert, profile.Key, profile.CA,
+		profile.MinVersion, profile.MaxVersion, strings.Join(profile.Ciphers, ","), profile.SNI, proxy)
+}
+
+// GetTLSConfig build TLS config from profile, certificates only loaded once
+func GetTLSConfig(profile libs.TLSConfig, proxy bool) (*tls.Config, error) {
+	fingerprint := tlsFingerprint(profile, proxy)
+	tlsMu.Lock()
+	defer tlsMu.Unlock()
+	if tlsCfg, ok := tlsConfigs[fingerprint]; ok {
+		return tlsCfg, nil
+	}
+	tlsCfg, err := BuildTLSConfig(profile, proxy)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfigs[fingerprint] = tlsCfg
+	return tlsCfg, nil
+}
+
+// BuildTLSConfig build TLS config from profile
+func BuildTLSConfig(profile libs.TLSConfig, proxy bool) (*tls.Config, error) {
+	tlsCfg := &tls.Config{
+		Renegotiation:            tls.RenegotiateOnceAsClient,
+		PreferServerCipherSuites: true,
+		InsecureSkipVerify:       true,
+		ServerName:               profile.SNI,
+	}
+
+	if profile.Cert != "" {
+		keyFile := profile.Key
+		if keyFile == "" {
+			keyFile = profile.Cert
+		}
+		cert, err := tls.LoadX509KeyPair(profile.Cert, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error loading client certificate: %v", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+
+	// only verify server certificate when CA bundle provided
+	if profile.CA != "" {
+		caData, err := ioutil.ReadFile(profile.CA)
+		if err != nil {
+			return nil, fmt.Errorf("error reading CA bundle: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caData) {
+			return nil, fmt.Errorf("no certificate found in CA bundle: %v", profile.CA)
+		}
+		tlsCfg.RootCAs = pool
+		tlsCfg.InsecureSkipVerify = false
+	}
+
+	if profile.MinVersion != "" {
+		version, err := parseTLSVersion(profile.MinVersion)
+		if err != nil {
+			return nil, err
+		}
+		tlsCfg.MinVersion = version
+	}
+	if profile.MaxVersion != "" {
+		version, err := parseTLSVersion(profile.MaxVersion)
+		if err != nil {
+			return nil, err
+		}
+		tlsCfg.MaxVersion = version
+	}
+
+	if len(profile.Ciphers) > 0 {
+		ciphers, err := parseCiphers(profile.Ciphers)
+		if err != nil {
+			return nil, err
+		}
+		tlsCfg.CipherSuites = ciphers
+	} else if proxy {
+		tlsCfg.CipherSuites = proxyCiphers
+	}
+	return tlsCfg, nil
+}
+
+// parseTLSVersion accept 1.2, tls1.2 or TLSv1.2
+func parseTLSVersion(raw string) (uint16, error) {
+	version := strings.ToLower(strings.TrimSpace(raw))
+	version = strings.TrimPrefix(strings.TrimPrefix(version, "tls"), "v")
+	if value, ok := tlsVersions[version]; ok {
+		return value, nil
+	}
+	return 0, fmt.Errorf("unknown TLS version: %v", raw)
+}
+
+// parseCiphers get cipher suite IDs by name
+func parseCiphers(names []string) ([]uint16, error) {
+	suites := make(map[string]uint16)
+	for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		suites[suite.Name] = suite.ID
+	}
+	var ciphers []uint16
+	for _, name := range names {
+		name = strings.ToUpper(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		id, ok := suites[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown cipher suite: %v", name)
+		}
+		ciphers = append(ciphers, id)
+	}
+	return ciphers, nil
+}
@@ -0,0 +1,77 @@
+package sender
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jaeles-project/jaeles/libs"
+)
+
+func writeClientCert(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "jaeles-client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile := filepath.Join(dir, "client.crt")
+	keyFile := filepath.Join(dir, "client.key")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+	return certFile, keyFile
+}
+
+func TestMutualTLS(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	ts.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	ts.StartTLS()
+	defer ts.Close()
+
+	certFile, keyFile := writeClientCert(t, t.TempDir())
+	var options libs.Options
+	options.Timeout = 5
+	req := libs.Request{Method: "GET", URL: ts.URL}
+
+	// no client certificate
+	if res, err := JustSend(options, req); err == nil && res.StatusCode == 200 {
+		t.Errorf("Expect handshake to fail without client certificate")
+	}
+
+	// certificate from request override
+	req.TLS = libs.TLSConfig{Cert: certFile, Key: keyFile, MinVersion: "1.2"}
+	res, err := JustSend(options, req)
+	if err != nil || res.Body != "jaeles-client" {
+		t.Errorf("Error sending mTLS request: %v %v", err, res.Body)
+	}
+
+	if _, err := BuildTLSConfig(libs.TLSConfig{Ciphers: []string{"TLS_FAKE"}}, false); err == nil {
+		t.Errorf("Expect unknown cipher to be rejected")
+	}
+}
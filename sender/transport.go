@@ -0,0 +1,83 @@
+package sender
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// transportKey identify a pooled transport
+type transportKey struct {
+	Proxy           string
+	Timeout         int
+	DisableCompress bool
+	TLS             string
+}
+
+var (
+	transportMu sync.Mutex
+	transports  = make(map[transportKey]*http.Transport)
+)
+
+// GetTransport return a transport shared by every request with the same settings
+func GetTransport(key transportKey, tlsCfg *tls.Config) (*http.Transport, error) {
+	transportMu.Lock()
+	defer transportMu.Unlock()
+	if transport, ok := transports[key]; ok {
+		return transport, nil
+	}
+	transport, err := NewTransport(key, tlsCfg)
+	if err != nil {
+		return nil, err
+	}
+	transports[key] = transport
+	return transport, nil
+}
+
+// NewTransport create a transport with keep alive enabled, invalid proxy is refused
+// so requests never go out directly when a proxy was asked for
+func NewTransport(key transportKey, tlsCfg *tls.Config) (*http.Transport, error) {
+	timeout := time.Duration(key.Timeout) * time.Second
+	transport := &http.Transport{
+		MaxIdleConns:          1000,
+		MaxIdleConnsPerHost:   100,
+		MaxConnsPerHost:       1000,
+		IdleConnTimeout:       timeout,
+		ExpectContinueTimeout: timeout,
+		ResponseHeaderTimeout: timeout,
+		TLSHandshakeTimeout:   timeout,
+		DisableCompression:    key.DisableCompress,
+		TLSClientConfig:       tlsCfg,
+	}
+	if key.Proxy != "" {
+		proxyURL, err := url.Parse(key.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy %v: %v", key.Proxy, err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	return transport, nil
+}
+
+// FreshTransport create a one time transport for request that doesn't want to reuse connection
+func FreshTransport(key transportKey, tlsCfg *tls.Config) (*http.Transport, error) {
+	transport, err := NewTransport(key, tlsCfg)
+	if err != nil {
+		return nil, err
+	}
+	transport.DisableKeepAlives = true
+	return transport, nil
+}
+
+// CloseTransports close idle connections of all pooled transports
+func CloseTransports() {
+	transportMu.Lock()
+	defer transportMu.Unlock()
+	for key, transport := range transports {
+		transport.CloseIdleConnections()
+		delete(transports, key)
+	}
+}
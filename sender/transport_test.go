@@ -0,0 +1,69 @@
+package sender
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jaeles-project/jaeles/libs"
+)
+
+func TestPooledTransport(t *testing.T) {
+	var newConns int32
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	ts.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&newConns, 1)
+		}
+	}
+	ts.Start()
+	defer ts.Close()
+	defer CloseTransports()
+
+	opt := libs.Options{Timeout: 5}
+	req := libs.Request{Method: "GET", URL: ts.URL}
+	for i := 0; i < 5; i++ {
+		res, err := JustSend(opt, req)
+		if err != nil || res.StatusCode != 200 {
+			t.Fatalf("Error sending request: %v", err)
+		}
+	}
+	if atomic.LoadInt32(&newConns) != 1 {
+		t.Errorf("Expected connection to be reused, got %v connections", newConns)
+	}
+
+	atomic.StoreInt32(&newConns, 0)
+	req.FreshConn = true
+	for i := 0; i < 3; i++ {
+		if _, err := JustSend(opt, req); err != nil {
+			t.Fatalf("Error sending request: %v", err)
+		}
+	}
+	if atomic.LoadInt32(&newConns) != 3 {
+		t.Errorf("Expected fresh connection per request, got %v connections", newConns)
+	}
+}
+
+func TestInvalidProxy(t *testing.T) {
+	var received int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&received, 1)
+	}))
+	defer ts.Close()
+	defer CloseTransports()
+
+	// request never go out directly when the proxy is invalid
+	opt := libs.Options{Timeout: 5, Proxy: "http://127.0.0.1:%zz"}
+	for _, fresh := range []bool{false, true} {
+		if _, err := JustSend(opt, libs.Request{Method: "GET", URL: ts.URL, FreshConn: fresh}); err == nil {
+			t.Errorf("Expect error with invalid proxy")
+		}
+	}
+	if atomic.LoadInt32(&received) != 0 {
+		t.Errorf("Expect no request sent without the proxy, got %v", received)
+	}
+}
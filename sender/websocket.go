@@ -0,0 +1,173 @@
+package sender
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/jaeles-project/jaeles/libs"
+	"github.com/jaeles-project/jaeles/utils"
+)
+
+// headers the websocket dialer set by itself
+var websocketSkipHeaders = map[string]bool{
+	"upgrade":                  true,
+	"connection":               true,
+	"sec-websocket-key":        true,
+	"sec-websocket-version":    true,
+	"sec-websocket-extensions": true,
+	"content-length":           true,
+}
+
+// SendWebSocket connect to websocket endpoint, send messages and collect received frames
+func SendWebSocket(options libs.Options, req libs.Request) (libs.Response, error) {
+	started := time.Now()
+	key := ReplayKey(req)
+	var res libs.Response
+	var err error
+	if options.ReplayDir != "" {
+		res, err = ReplayFixture(options, key, req)
+	} else {
+		res, err = sendWebSocket(options, req)
+	}
+	RecordHAR(options, req, res, err, started)
+	RecordFixture(options, key, req, res, err)
+	return res, err
+}
+
+func sendWebSocket(options libs.Options, req libs.Request) (libs.Response, error) {
+	var res libs.Response
+	wsURL := WebSocketURL(req.URL)
+	if options.Verbose {
+		fmt.Printf("[Sent][WebSocket] %v\n", wsURL)
+	}
+
+	proxy, pooled, err := RequestProxy(options, req)
+	if err != nil {
+		return res, err
+	}
+
+	timeout := options.Timeout
+	if timeout <= 0 {
+		timeout = 20
+	}
+	tlsCfg, err := GetTLSConfig(MergeTLS(options.TLS, req.TLS), isHTTPProxy(proxy))
+	if err != nil {
+		utils.ErrorF("%v", err)
+		return res, err
+	}
+	dialer := websocket.Dialer{
+		HandshakeTimeout: time.Duration(timeout) * time.Second,
+		TLSClientConfig:  tlsCfg,
+		Jar:              req.Jar,
+	}
+	if proxy != "" {
+		proxyURL, err := url.Parse(proxy)
+		if err != nil {
+			return res, err
+		}
+		dialer.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	headers := make(http.Header)
+	for _, header := range req.Headers {
+		for k, v := range header {
+			if websocketSkipHeaders[strings.ToLower(k)] {
+				continue
+			}
+			headers.Set(k, v)
+		}
+	}
+
+	timeStart := time.Now()
+	conn, resp, err := dialer.Dial(wsURL, headers)
+	if pooled {
+		proxyPool.Report(proxy, err)
+	}
+	if resp != nil {
+		res.StatusCode = resp.StatusCode
+		res.Status = fmt.Sprintf("%v %v", resp.Status, resp.Proto)
+		var keys []string
+		for k := range resp.Header {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			res.Headers = append(res.Headers, map[string]string{k: strings.Join(resp.Header[k], "")})
+		}
+	}
+	if err != nil {
+		// handshake rejected, keep what the server said
+		if resp != nil && resp.Body != nil {
+			body, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			res.Body = string(body)
+		}
+		res.ResponseTime = time.Since(timeStart).Seconds()
+		res.Length = len(res.Body)
+		res.Beautify = BeautifyResponse(res)
+		utils.ErrorF("%v", err)
+		return res, err
+	}
+	defer conn.Close()
+
+	messages := req.Messages
+	if len(messages) == 0 && req.Body != "" {
+		messages = []string{req.Body}
+	}
+	for _, message := range messages {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(message)); err != nil {
+			utils.ErrorF("Error sending websocket message: %v", err)
+			return res, err
+		}
+	}
+
+	// collect frames until the window is over or the server close the connection
+	window := 2
+	if req.Timeout > 0 {
+		window = req.Timeout
+	}
+	conn.SetReadDeadline(time.Now().Add(time.Duration(window) * time.Second))
+	var frames []string
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			var netErr net.Error
+			if !errors.As(err, &netErr) || !netErr.Timeout() {
+				utils.DebugF("websocket closed: %v", err)
+			}
+			break
+		}
+		frames = append(frames, string(data))
+	}
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+
+	res.Body = strings.Join(frames, "\n")
+	res.ResponseTime = time.Since(timeStart).Seconds()
+	res.Length = len(res.Body)
+	res.Headers = append(res.Headers,
+		map[string]string{"Total Length": strconv.Itoa(res.Length)},
+		map[string]string{"Response Time": fmt.Sprintf("%f", res.ResponseTime)},
+	)
+	res.Beautify = BeautifyResponse(res)
+	return res, nil
+}
+
+// WebSocketURL convert http scheme to ws scheme
+func WebSocketURL(raw string) string {
+	switch {
+	case strings.HasPrefix(raw, "https://"):
+		return "wss://" + strings.TrimPrefix(raw, "https://")
+	case strings.HasPrefix(raw, "http://"):
+		return "ws://" + strings.TrimPrefix(raw, "http://")
+	}
+	return raw
+}
@@ -0,0 +1,58 @@
+package sender
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/jaeles-project/jaeles/libs"
+)
+
+func TestSendWebSocket(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Token") != "secret" {
+			http.Error(w, "denied", http.StatusForbidden)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.WriteMessage(websocket.TextMessage, []byte("welcome"))
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			conn.WriteMessage(websocket.TextMessage, []byte("echo: "+string(data)))
+		}
+	}))
+	defer ts.Close()
+
+	var options libs.Options
+	options.Timeout = 5
+	req := libs.Request{
+		Engine:   "websocket",
+		URL:      ts.URL + "/ws",
+		Timeout:  1,
+		Headers:  []map[string]string{{"X-Token": "secret"}, {"Connection": "keep-alive"}},
+		Messages: []string{`{"id":1}`, "jaeles"},
+	}
+	res, err := SendWebSocket(options, req)
+	if err != nil {
+		t.Fatalf("Error sending websocket: %v", err)
+	}
+	if res.StatusCode != 101 || res.Body != "welcome\necho: {\"id\":1}\necho: jaeles" {
+		t.Errorf("Unexpected response: %v %v", res.StatusCode, res.Body)
+	}
+
+	req.Headers = nil
+	res, err = SendWebSocket(options, req)
+	if err == nil || res.StatusCode != 403 || !strings.Contains(res.Body, "denied") {
+		t.Errorf("Expect rejected handshake, got %v %v %v", res.StatusCode, res.Body, err)
+	}
+}